@@ -0,0 +1,261 @@
+// Package abi encodes Go values as Solidity ABI encoded bytes, so bridge results
+// can be returned ready for on-chain consumption.
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// Encode ABI encodes the values as the Solidity type signature, as done by
+// `abi.encode` in Solidity. A tuple signature such as `(uint256,string,address[])`
+// is given a value per component, otherwise a single value is given.
+//
+// Values are converted from Go types as follows:
+//  - Integers from any Go integer, *big.Int, or decimal or `0x` prefixed hex string
+//  - Addresses from a `0x` prefixed hex string, or a [20]byte or []byte of length 20
+//  - Booleans from a bool
+//  - Fixed and dynamic bytes from a []byte, byte array, `0x` prefixed hex string, or any other string as text
+//  - Strings from a string
+//  - Arrays from a slice or array of the element values
+//  - Tuples from a slice or array of the component values, or a struct with the components as fields
+func Encode(sig string, values ...interface{}) ([]byte, error) {
+	t, err := ParseType(sig)
+	if err != nil {
+		return nil, err
+	}
+	if t.Kind != TupleKind {
+		t = &Type{Kind: TupleKind, Components: []*Type{t}, raw: "(" + t.raw + ")"}
+	}
+	if len(values) != len(t.Components) {
+		return nil, fmt.Errorf("Expected %d values for %s, got %d", len(t.Components), t, len(values))
+	}
+	vs := make([]reflect.Value, len(values))
+	for i, v := range values {
+		vs[i] = reflect.ValueOf(v)
+	}
+	return encodeTuple(t.Components, vs)
+}
+
+// EncodeToHex mirrors Encode, returning the encoding as a `0x` prefixed hex string
+func EncodeToHex(sig string, values ...interface{}) (string, error) {
+	b, err := Encode(sig, values...)
+	if err != nil {
+		return "", err
+	}
+	return "0x" + hex.EncodeToString(b), nil
+}
+
+// encodeTuple encodes the values as the components of a tuple, with the
+// dynamic values appended after the head and referenced by their offset
+func encodeTuple(ts []*Type, vs []reflect.Value) ([]byte, error) {
+	var hs int
+	for _, t := range ts {
+		hs += t.headSize()
+	}
+	var head, tail []byte
+	for i, t := range ts {
+		b, err := encode(t, vs[i])
+		if err != nil {
+			return nil, err
+		}
+		if t.IsDynamic() {
+			head = append(head, encodeLength(hs+len(tail))...)
+			tail = append(tail, b...)
+		} else {
+			head = append(head, b...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+func encode(t *Type, v reflect.Value) ([]byte, error) {
+	for v.IsValid() && v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, fmt.Errorf("Missing value for %s", t)
+	}
+
+	switch t.Kind {
+	case UintKind, IntKind:
+		n, err := toBigInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s value: %v", t, err)
+		}
+		return encodeInt(t, n)
+	case AddressKind:
+		b, err := toBytes(v)
+		if err != nil || len(b) != 20 {
+			return nil, fmt.Errorf("Invalid address value: %v", v.Interface())
+		}
+		return leftPad(b), nil
+	case BoolKind:
+		if v.Kind() != reflect.Bool {
+			return nil, fmt.Errorf("Invalid bool value: %v", v.Interface())
+		}
+		b := make([]byte, 32)
+		if v.Bool() {
+			b[31] = 1
+		}
+		return b, nil
+	case FixedBytesKind:
+		b, err := toBytes(v)
+		if err != nil || len(b) > t.Size {
+			return nil, fmt.Errorf("Invalid %s value: %v", t, v.Interface())
+		}
+		// Always a full word, even when empty
+		w := make([]byte, 32)
+		copy(w, b)
+		return w, nil
+	case BytesKind:
+		b, err := toBytes(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid bytes value: %v", v.Interface())
+		}
+		return append(encodeLength(len(b)), rightPad(b)...), nil
+	case StringKind:
+		if v.Kind() != reflect.String {
+			return nil, fmt.Errorf("Invalid string value: %v", v.Interface())
+		}
+		b := []byte(v.String())
+		return append(encodeLength(len(b)), rightPad(b)...), nil
+	case SliceKind, ArrayKind:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("Invalid %s value: %v", t, v.Interface())
+		}
+		if t.Kind == ArrayKind && v.Len() != t.Length {
+			return nil, fmt.Errorf("Invalid %s value: expected %d elements, got %d", t, t.Length, v.Len())
+		}
+		ts := make([]*Type, v.Len())
+		vs := make([]reflect.Value, v.Len())
+		for i := range vs {
+			ts[i], vs[i] = t.Elem, v.Index(i)
+		}
+		b, err := encodeTuple(ts, vs)
+		if err != nil || t.Kind == ArrayKind {
+			return b, err
+		}
+		return append(encodeLength(v.Len()), b...), nil
+	case TupleKind:
+		vs, err := tupleValues(t, v)
+		if err != nil {
+			return nil, err
+		}
+		return encodeTuple(t.Components, vs)
+	}
+	return nil, fmt.Errorf("Unsupported type: %s", t)
+}
+
+func encodeInt(t *Type, n *big.Int) ([]byte, error) {
+	max := new(big.Int).Lsh(big.NewInt(1), uint(t.Size))
+	min := new(big.Int)
+	if t.Kind == IntKind {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("Value out of range of %s: %s", t, n)
+	}
+	if n.Sign() < 0 {
+		// two's complement
+		n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return leftPad(n.Bytes()), nil
+}
+
+func encodeLength(n int) []byte {
+	return leftPad(big.NewInt(int64(n)).Bytes())
+}
+
+func tupleValues(t *Type, v reflect.Value) ([]reflect.Value, error) {
+	var vs []reflect.Value
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			vs = append(vs, v.Index(i))
+		}
+	case reflect.Ptr:
+		return tupleValues(t, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if len(v.Type().Field(i).PkgPath) == 0 {
+				vs = append(vs, v.Field(i))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Invalid %s value: %v", t, v.Interface())
+	}
+	if len(vs) != len(t.Components) {
+		return nil, fmt.Errorf("Invalid %s value: expected %d components, got %d", t, len(t.Components), len(vs))
+	}
+	return vs, nil
+}
+
+func toBigInt(v reflect.Value) (*big.Int, error) {
+	switch n := v.Interface().(type) {
+	case *big.Int:
+		if n == nil {
+			return nil, fmt.Errorf("nil integer")
+		}
+		return n, nil
+	case big.Int:
+		return &n, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(v.Uint()), nil
+	case reflect.String:
+		s, base := v.String(), 10
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			s, base = s[2:], 16
+		}
+		if n, ok := new(big.Int).SetString(s, base); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("not an integer: %s", v.String())
+	}
+	return nil, fmt.Errorf("not an integer: %v", v.Interface())
+}
+
+func toBytes(v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			return hex.DecodeString(s[2:])
+		}
+		return []byte(s), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		b := make([]byte, v.Len())
+		for i := range b {
+			b[i] = byte(v.Index(i).Uint())
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("not bytes: %v", v.Interface())
+}
+
+// leftPad pads the bytes to a multiple of 32 with leading zeros
+func leftPad(b []byte) []byte {
+	n := (len(b) + 31) / 32 * 32
+	if n == 0 {
+		n = 32
+	}
+	return append(make([]byte, n-len(b)), b...)
+}
+
+// rightPad pads the bytes to a multiple of 32 with trailing zeros
+func rightPad(b []byte) []byte {
+	n := (len(b) + 31) / 32 * 32
+	return append(b[:len(b):len(b)], make([]byte, n-len(b))...)
+}
@@ -0,0 +1,163 @@
+package abi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func words(ws ...string) string {
+	return "0x" + strings.Join(ws, "")
+}
+
+func TestEncode_SolidityExamples(t *testing.T) {
+	h, err := EncodeToHex(
+		"(uint256,uint32[],bytes10,bytes)",
+		0x123,
+		[]uint32{0x456, 0x789},
+		"1234567890",
+		[]byte("Hello, world!"),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, words(
+		"0000000000000000000000000000000000000000000000000000000000000123",
+		"0000000000000000000000000000000000000000000000000000000000000080",
+		"3132333435363738393000000000000000000000000000000000000000000000",
+		"00000000000000000000000000000000000000000000000000000000000000e0",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+		"0000000000000000000000000000000000000000000000000000000000000456",
+		"0000000000000000000000000000000000000000000000000000000000000789",
+		"000000000000000000000000000000000000000000000000000000000000000d",
+		"48656c6c6f2c20776f726c642100000000000000000000000000000000000000",
+	), h)
+
+	h, err = EncodeToHex(
+		"(uint256[][],string[])",
+		[][]int{{1, 2}, {3}},
+		[]string{"one", "two", "three"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, words(
+		"0000000000000000000000000000000000000000000000000000000000000040",
+		"0000000000000000000000000000000000000000000000000000000000000140",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+		"0000000000000000000000000000000000000000000000000000000000000040",
+		"00000000000000000000000000000000000000000000000000000000000000a0",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"0000000000000000000000000000000000000000000000000000000000000060",
+		"00000000000000000000000000000000000000000000000000000000000000a0",
+		"00000000000000000000000000000000000000000000000000000000000000e0",
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"6f6e650000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"74776f0000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000005",
+		"7468726565000000000000000000000000000000000000000000000000000000",
+	), h)
+}
+
+func TestEncode_MultiWordResponse(t *testing.T) {
+	h, err := EncodeToHex(
+		"(uint256,int256,bytes32,string,address[])",
+		big.NewInt(1),
+		"-1",
+		"ETH",
+		"a",
+		[]string{"0x0000000000000000000000000000000000000001"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, words(
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"4554480000000000000000000000000000000000000000000000000000000000",
+		"00000000000000000000000000000000000000000000000000000000000000a0",
+		"00000000000000000000000000000000000000000000000000000000000000e0",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"6100000000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+	), h)
+}
+
+func TestEncode_SingleValue(t *testing.T) {
+	h, err := EncodeToHex("bool", true)
+	assert.Nil(t, err)
+	assert.Equal(t, words("0000000000000000000000000000000000000000000000000000000000000001"), h)
+
+	type pair struct {
+		A uint8
+		B bool
+	}
+	h, err = EncodeToHex("(uint8,bool)[2]", []pair{{1, false}, {2, true}})
+	assert.Nil(t, err)
+	assert.Equal(t, words(
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+	), h)
+}
+
+func TestEncode_EmptyValues(t *testing.T) {
+	zero := "0000000000000000000000000000000000000000000000000000000000000000"
+
+	h, err := EncodeToHex("bytes32", "")
+	assert.Nil(t, err)
+	assert.Equal(t, words(zero), h)
+
+	// Empty fixed bytes keep their word in a tuple, with dynamic tails
+	// of empty bytes and strings being only their length
+	h, err = EncodeToHex("(bytes4,string,bytes32,bytes)", "", "", []byte{}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, words(
+		zero,
+		"0000000000000000000000000000000000000000000000000000000000000080",
+		zero,
+		"00000000000000000000000000000000000000000000000000000000000000a0",
+		zero,
+		zero,
+	), h)
+}
+
+func TestEncode_Errors(t *testing.T) {
+	tests := []struct {
+		sig    string
+		values []interface{}
+		err    string
+	}{
+		{"uint8", []interface{}{256}, "Value out of range of uint8: 256"},
+		{"uint256", []interface{}{-1}, "Value out of range of uint256: -1"},
+		{"int8", []interface{}{-129}, "Value out of range of int8: -129"},
+		{"uint256", []interface{}{"1.5"}, "Invalid uint256 value: not an integer: 1.5"},
+		{"address", []interface{}{"0x01"}, "Invalid address value: 0x01"},
+		{"bytes2", []interface{}{"abc"}, "Invalid bytes2 value: abc"},
+		{"uint256[2]", []interface{}{[]int{1}}, "Invalid uint256[2] value: expected 2 elements, got 1"},
+		{"(uint256,bool)", []interface{}{1}, "Expected 2 values for (uint256,bool), got 1"},
+		{"fixed128x18", []interface{}{1}, "Unsupported type: fixed128x18"},
+		{"uint7", []interface{}{1}, "Invalid integer type: uint7"},
+	}
+	for _, test := range tests {
+		t.Run(test.sig, func(t *testing.T) {
+			_, err := Encode(test.sig, test.values...)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestParseType(t *testing.T) {
+	typ, err := ParseType("(uint, int, (address,bytes)[][2], byte)")
+	assert.Nil(t, err)
+	assert.Equal(t, "(uint256,int256,(address,bytes)[][2],bytes1)", typ.String())
+	assert.True(t, typ.IsDynamic())
+	assert.Equal(t, ArrayKind, typ.Components[2].Kind)
+	assert.Equal(t, SliceKind, typ.Components[2].Elem.Kind)
+
+	_, err = ParseType("(uint256")
+	assert.EqualError(t, err, "Invalid tuple type: (uint256")
+}
@@ -0,0 +1,194 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a Solidity type
+type Kind int
+
+// Supported kinds of Solidity types
+const (
+	UintKind Kind = iota
+	IntKind
+	AddressKind
+	BoolKind
+	FixedBytesKind
+	BytesKind
+	StringKind
+	SliceKind
+	ArrayKind
+	TupleKind
+)
+
+// Type is a parsed Solidity type, such as `uint256`, `bytes32[2]` or `(string,address[])`
+type Type struct {
+	Kind Kind
+	// Size is the bits of an integer type or bytes of a fixed bytes type
+	Size int
+	// Length is the length of a fixed size array type
+	Length int
+	// Elem is the element type of an array type
+	Elem *Type
+	// Components are the types of a tuple type
+	Components []*Type
+
+	raw string
+}
+
+// ParseType parses the Solidity type signature. The `uint`, `int` and `byte`
+// aliases are given as `uint256`, `int256` and `bytes1`.
+func ParseType(sig string) (*Type, error) {
+	sig = strings.Replace(sig, " ", "", -1)
+	if len(sig) == 0 {
+		return nil, fmt.Errorf("Empty type")
+	}
+
+	// array types are parsed from their outermost dimension, which is the last
+	if strings.HasSuffix(sig, "]") {
+		i := strings.LastIndex(sig, "[")
+		if i < 0 {
+			return nil, fmt.Errorf("Invalid type: %s", sig)
+		}
+		elem, err := ParseType(sig[:i])
+		if err != nil {
+			return nil, err
+		}
+		if l := sig[i+1 : len(sig)-1]; len(l) == 0 {
+			return &Type{Kind: SliceKind, Elem: elem, raw: elem.raw + "[]"}, nil
+		} else if n, err := strconv.Atoi(l); err != nil || n <= 0 {
+			return nil, fmt.Errorf("Invalid array length in type: %s", sig)
+		} else {
+			return &Type{Kind: ArrayKind, Length: n, Elem: elem, raw: fmt.Sprintf("%s[%d]", elem.raw, n)}, nil
+		}
+	}
+
+	if strings.HasPrefix(sig, "(") {
+		if !strings.HasSuffix(sig, ")") {
+			return nil, fmt.Errorf("Invalid tuple type: %s", sig)
+		}
+		parts, err := splitTuple(sig[1 : len(sig)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid tuple type: %s", sig)
+		}
+		t := &Type{Kind: TupleKind}
+		raws := make([]string, len(parts))
+		for i, p := range parts {
+			c, err := ParseType(p)
+			if err != nil {
+				return nil, err
+			}
+			t.Components = append(t.Components, c)
+			raws[i] = c.raw
+		}
+		t.raw = "(" + strings.Join(raws, ",") + ")"
+		return t, nil
+	}
+
+	switch {
+	case sig == "address":
+		return &Type{Kind: AddressKind, raw: sig}, nil
+	case sig == "bool":
+		return &Type{Kind: BoolKind, raw: sig}, nil
+	case sig == "string":
+		return &Type{Kind: StringKind, raw: sig}, nil
+	case sig == "bytes":
+		return &Type{Kind: BytesKind, raw: sig}, nil
+	case sig == "byte":
+		return &Type{Kind: FixedBytesKind, Size: 1, raw: "bytes1"}, nil
+	case strings.HasPrefix(sig, "bytes"):
+		n, err := strconv.Atoi(sig[5:])
+		if err != nil || n < 1 || n > 32 {
+			return nil, fmt.Errorf("Invalid bytes type: %s", sig)
+		}
+		return &Type{Kind: FixedBytesKind, Size: n, raw: sig}, nil
+	case strings.HasPrefix(sig, "uint"):
+		return parseInt(UintKind, sig, sig[4:])
+	case strings.HasPrefix(sig, "int"):
+		return parseInt(IntKind, sig, sig[3:])
+	}
+	return nil, fmt.Errorf("Unsupported type: %s", sig)
+}
+
+func parseInt(k Kind, sig, size string) (*Type, error) {
+	if len(size) == 0 {
+		return &Type{Kind: k, Size: 256, raw: sig + "256"}, nil
+	}
+	n, err := strconv.Atoi(size)
+	if err != nil || n < 8 || n > 256 || n%8 != 0 {
+		return nil, fmt.Errorf("Invalid integer type: %s", sig)
+	}
+	return &Type{Kind: k, Size: n, raw: sig}, nil
+}
+
+// splitTuple splits the components of a tuple by the commas
+// that aren't within a nested tuple
+func splitTuple(s string) ([]string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	var parts []string
+	var depth, start int
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(parts, s[start:]), nil
+}
+
+// String returns the canonical signature of the type
+func (t *Type) String() string {
+	return t.raw
+}
+
+// IsDynamic returns whether the encoding of the type has a dynamic size,
+// so it's referenced by an offset within a tuple
+func (t *Type) IsDynamic() bool {
+	switch t.Kind {
+	case BytesKind, StringKind, SliceKind:
+		return true
+	case ArrayKind:
+		return t.Elem.IsDynamic()
+	case TupleKind:
+		for _, c := range t.Components {
+			if c.IsDynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize returns the size of the type within the head of a tuple
+func (t *Type) headSize() int {
+	if t.IsDynamic() {
+		return 32
+	}
+	switch t.Kind {
+	case ArrayKind:
+		return t.Length * t.Elem.headSize()
+	case TupleKind:
+		var n int
+		for _, c := range t.Components {
+			n += c.headSize()
+		}
+		return n
+	}
+	return 32
+}
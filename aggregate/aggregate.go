@@ -0,0 +1,294 @@
+// Package aggregate fetches values from many sources concurrently and aggregates
+// them into a single value, such as the median price across exchanges.
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linkpoolio/bridges"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPrecision is the number of decimals the aggregated
+// value is given with when no precision is given
+const DefaultPrecision = 18
+
+// ErrQuorum is returned when too few sources gave a valid value
+var ErrQuorum = errors.New("not enough valid responses")
+
+// Source is a source of a value to be aggregated
+type Source struct {
+	Name  string
+	Fetch func(ctx context.Context) (Value, error)
+}
+
+// HTTP returns a Source that calls the url with the helper, so the bridge's http
+// client, retries and caching are used, taking the value at the path of the
+// JSON response. The volume is taken from the volume path if it's given. Paths
+// use the gjson syntax, such as `data.price`, or JSONPath when starting with `$`,
+// such as `$.data[0].price`, limited to child names and array indexes.
+func HTTP(h *bridges.Helper, url, path, volumePath string, opts bridges.CallOpts) Source {
+	gp, perr := gjsonPath(path)
+	var gvp string
+	if perr == nil && len(volumePath) > 0 {
+		gvp, perr = gjsonPath(volumePath)
+	}
+	return Source{
+		Name: url,
+		Fetch: func(ctx context.Context) (Value, error) {
+			var v Value
+			if perr != nil {
+				return v, perr
+			}
+			b, err := h.HTTPCallRawWithOptsWithContext(ctx, http.MethodGet, url, opts)
+			if err != nil {
+				return v, err
+			}
+			j, err := bridges.Parse(b)
+			if err != nil {
+				return v, err
+			}
+			var ok bool
+			if v.Value, ok = j.GetDecimal(gp); !ok {
+				return v, fmt.Errorf("No number at path %q", path)
+			}
+			if len(volumePath) > 0 {
+				if v.Volume, ok = j.GetDecimal(gvp); !ok {
+					return v, fmt.Errorf("No number at volume path %q", volumePath)
+				}
+			}
+			return v, nil
+		},
+	}
+}
+
+// gjsonPath returns the gjson path of a JSONPath starting with `$`, such
+// as `$.data[0].price` or `$['data'][0]['price']` for `data.0.price`. Any
+// other path is taken as a gjson path already. Wildcards, filters, slices
+// and recursive descent have no gjson equivalent, so an error is returned.
+func gjsonPath(p string) (string, error) {
+	if !strings.HasPrefix(p, "$") {
+		return p, nil
+	}
+	unsupported := func() (string, error) {
+		return "", fmt.Errorf("Unsupported JSONPath %q, only child names and array indexes can be used", p)
+	}
+
+	var keys []string
+	rest := p[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			i := strings.IndexAny(rest[1:], ".[") + 1
+			if i == 0 {
+				i = len(rest)
+			}
+			name := rest[1:i]
+			if len(name) == 0 || name == "*" {
+				return unsupported()
+			}
+			keys = append(keys, escapeKey(name))
+			rest = rest[i:]
+		case '[':
+			i := strings.IndexByte(rest, ']')
+			if i < 0 {
+				return unsupported()
+			}
+			sel := rest[1:i]
+			if len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[len(sel)-1] == sel[0] {
+				keys = append(keys, escapeKey(sel[1:len(sel)-1]))
+			} else if _, err := strconv.ParseUint(sel, 10, 0); err == nil {
+				keys = append(keys, sel)
+			} else {
+				return unsupported()
+			}
+			rest = rest[i+1:]
+		default:
+			return unsupported()
+		}
+	}
+	if len(keys) == 0 {
+		return unsupported()
+	}
+	return strings.Join(keys, "."), nil
+}
+
+// escapeKey escapes the characters of an object key that have
+// a special meaning in gjson paths
+func escapeKey(k string) string {
+	var sb strings.Builder
+	for _, c := range k {
+		if strings.ContainsRune(`\.*?|#@!`, c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// Options are the options for aggregating values
+type Options struct {
+	// Method is the aggregation method, defaulting to Mean
+	Method Method
+	// Timeout is the maximum duration given to each source. Zero means
+	// only the given context's deadline applies.
+	Timeout time.Duration
+	// MinResponses is the quorum of valid values required after any
+	// outliers are rejected, defaulting to one
+	MinResponses int
+	// TrimFraction is the fraction of values removed from each end
+	// for TrimmedMean, defaulting to DefaultTrimFraction
+	TrimFraction float64
+	// Outliers is the method of rejecting outliers, where none are
+	// rejected if it's not set
+	Outliers OutlierMethod
+	// OutlierThreshold is the threshold of the outlier method,
+	// defaulting to DefaultMADThreshold or DefaultPercentThreshold
+	OutlierThreshold float64
+	// Precision is the number of decimals the aggregated value is
+	// given with, defaulting to DefaultPrecision
+	Precision int
+}
+
+// Result is the aggregated value, along with diagnostics of each source
+type Result struct {
+	Method    Method         `json:"method"`
+	Result    string         `json:"result"`
+	Value     *big.Rat       `json:"-"`
+	Responses int            `json:"responses"`
+	Failed    int            `json:"failed"`
+	Outliers  int            `json:"outliers"`
+	Sources   []SourceResult `json:"sources"`
+}
+
+// SourceResult is the outcome of fetching the value of a source
+type SourceResult struct {
+	Name    string `json:"name"`
+	Value   string `json:"value,omitempty"`
+	Volume  string `json:"volume,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Outlier bool   `json:"outlier"`
+	Latency string `json:"latency"`
+}
+
+// Aggregate fetches the values of the sources concurrently, rejects any outliers
+// and aggregates the rest. If fewer valid values than the quorum are given, then
+// an error wrapping ErrQuorum is returned along with the result diagnostics.
+func Aggregate(ctx context.Context, sources []Source, opts Options) (*Result, error) {
+	if len(opts.Method) == 0 {
+		opts.Method = Mean
+	}
+	if opts.MinResponses <= 0 {
+		opts.MinResponses = 1
+	}
+	if opts.Precision <= 0 {
+		opts.Precision = DefaultPrecision
+	}
+
+	r := &Result{Method: opts.Method, Sources: make([]SourceResult, len(sources))}
+	values := make([]Value, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+	for i, s := range sources {
+		go func(i int, s Source) {
+			defer wg.Done()
+			start := time.Now()
+			values[i], errs[i] = fetch(ctx, s, opts.Timeout)
+			r.Sources[i] = SourceResult{Name: s.Name, Latency: time.Since(start).String()}
+		}(i, s)
+	}
+	wg.Wait()
+
+	var valid []Value
+	var vs []*big.Rat
+	var idx []int
+	for i, err := range errs {
+		if err != nil {
+			r.Failed++
+			r.Sources[i].Error = err.Error()
+			continue
+		}
+		r.Sources[i].Value = format(values[i].Value, opts.Precision)
+		if values[i].Volume != nil {
+			r.Sources[i].Volume = format(values[i].Volume, opts.Precision)
+		}
+		vs = append(vs, values[i].Value)
+		idx = append(idx, i)
+	}
+
+	out, err := outliers(opts.Outliers, vs, opts.OutlierThreshold)
+	if err != nil {
+		return r, err
+	}
+	for j, o := range out {
+		if o {
+			r.Outliers++
+			r.Sources[idx[j]].Outlier = true
+		} else {
+			valid = append(valid, values[idx[j]])
+		}
+	}
+
+	r.Responses = len(valid)
+	if r.Responses < opts.MinResponses {
+		return r, fmt.Errorf("Only %d of %d sources gave a valid value, %d required: %w",
+			r.Responses, len(sources), opts.MinResponses, ErrQuorum)
+	}
+
+	if r.Value, err = Compute(opts.Method, valid, opts.TrimFraction); err != nil {
+		return r, err
+	}
+	r.Result = format(r.Value, opts.Precision)
+	return r, nil
+}
+
+// fetch fetches the value of the source, giving up once the
+// timeout is exceeded even if the source ignores its context
+func fetch(ctx context.Context, s Source, timeout time.Duration) (Value, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type output struct {
+		v   Value
+		err error
+	}
+	oc := make(chan output, 1)
+	go func() {
+		v, err := s.Fetch(ctx)
+		oc <- output{v, err}
+	}()
+
+	select {
+	case out := <-oc:
+		if out.err == nil && out.v.Value == nil {
+			out.err = errors.New("No value given")
+		}
+		return out.v, out.err
+	case <-ctx.Done():
+		return Value{}, ctx.Err()
+	}
+}
+
+// format returns the value as a decimal with the precision,
+// removing any trailing zeros
+func format(v *big.Rat, precision int) string {
+	s := v.FloatString(precision)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
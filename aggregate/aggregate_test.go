@@ -0,0 +1,189 @@
+package aggregate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/linkpoolio/bridges"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func rat(s string) *big.Rat {
+	r, _ := new(big.Rat).SetString(s)
+	return r
+}
+
+func values(vs ...string) []Value {
+	var out []Value
+	for _, v := range vs {
+		out = append(out, Value{Value: rat(v)})
+	}
+	return out
+}
+
+func staticSource(name, value string) Source {
+	return Source{
+		Name: name,
+		Fetch: func(ctx context.Context) (Value, error) {
+			return Value{Value: rat(value)}, nil
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	vs := values("1", "2", "2", "3", "100")
+	tests := []struct {
+		method Method
+		values []Value
+		want   string
+	}{
+		{Mean, vs, "108/5"},
+		{Median, vs, "2"},
+		{Median, values("1", "2", "3", "4"), "5/2"},
+		{Mode, vs, "2"},
+		{Mode, values("3", "1", "2"), "2"},
+		{TrimmedMean, vs, "7/3"},
+		{Min, vs, "1"},
+		{Max, vs, "100"},
+		{VWAP, []Value{{rat("10"), rat("1")}, {rat("20"), rat("3")}}, "35/2"},
+		{Mean, values("0.000000000000000001", "0.000000000000000002"), "3/2000000000000000000"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.method), func(t *testing.T) {
+			v, err := Compute(test.method, test.values, 0.2)
+			assert.Nil(t, err)
+			assert.Equal(t, test.want, v.RatString())
+		})
+	}
+
+	_, err := Compute(VWAP, vs, 0)
+	assert.EqualError(t, err, "Volume weighted mean requires a volume for every value")
+	_, err = Compute("geometric", vs, 0)
+	assert.EqualError(t, err, "Unsupported aggregation method: geometric")
+	_, err = Compute(Mean, nil, 0)
+	assert.EqualError(t, err, "No values to aggregate")
+}
+
+func TestAggregate_Outliers(t *testing.T) {
+	sources := []Source{
+		staticSource("a", "100"),
+		staticSource("b", "101"),
+		staticSource("c", "99"),
+		staticSource("d", "100.5"),
+		staticSource("e", "150"),
+	}
+	for _, method := range []OutlierMethod{MAD, PercentDeviation} {
+		t.Run(string(method), func(t *testing.T) {
+			r, err := Aggregate(context.Background(), sources, Options{Method: Mean, Outliers: method})
+			assert.Nil(t, err)
+			assert.Equal(t, "100.125", r.Result)
+			assert.Equal(t, 4, r.Responses)
+			assert.Equal(t, 1, r.Outliers)
+			assert.True(t, r.Sources[4].Outlier)
+			assert.Equal(t, "150", r.Sources[4].Value)
+		})
+	}
+
+	r, err := Aggregate(context.Background(), sources, Options{Outliers: PercentDeviation, OutlierThreshold: 60})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, r.Outliers)
+}
+
+func TestAggregate_Quorum(t *testing.T) {
+	sources := []Source{
+		staticSource("a", "1"),
+		{Name: "failing", Fetch: func(ctx context.Context) (Value, error) {
+			return Value{}, errors.New("upstream down")
+		}},
+		{Name: "slow", Fetch: func(ctx context.Context) (Value, error) {
+			time.Sleep(time.Second)
+			return Value{Value: rat("2")}, nil
+		}},
+	}
+
+	r, err := Aggregate(context.Background(), sources, Options{
+		Timeout:      50 * time.Millisecond,
+		MinResponses: 2,
+	})
+	assert.True(t, errors.Is(err, ErrQuorum))
+	assert.EqualError(t, err, "Only 1 of 3 sources gave a valid value, 2 required: not enough valid responses")
+	assert.Equal(t, 2, r.Failed)
+	assert.Equal(t, "upstream down", r.Sources[1].Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), r.Sources[2].Error)
+
+	r, err = Aggregate(context.Background(), sources, Options{Timeout: 50 * time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", r.Result)
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"data":{"price":"%s","volume":2}}`, r.URL.Query().Get("price"))
+	}))
+	defer srv.Close()
+
+	h := bridges.NewHelper(nil)
+	sources := []Source{
+		HTTP(h, srv.URL+"?price=1000.000000000000000001", "$.data.price", "data.volume", bridges.CallOpts{}),
+		HTTP(h, srv.URL+"?price=1000.000000000000000003", "data.price", "data.volume", bridges.CallOpts{}),
+		HTTP(h, srv.URL+"?price=abc", "data.price", "", bridges.CallOpts{}),
+	}
+	r, err := Aggregate(context.Background(), sources, Options{Method: VWAP, MinResponses: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "1000.000000000000000002", r.Result)
+	assert.Equal(t, "2", r.Sources[0].Volume)
+	assert.Equal(t, `No number at path "data.price"`, r.Sources[2].Error)
+}
+
+func TestHTTP_JSONPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"price":"10","volume":2}]}`)
+	}))
+	defer srv.Close()
+
+	h := bridges.NewHelper(nil)
+	sources := []Source{
+		HTTP(h, srv.URL, "$.data[0].price", "$['data'][0]['volume']", bridges.CallOpts{}),
+		HTTP(h, srv.URL, "$..price", "", bridges.CallOpts{}),
+	}
+	r, err := Aggregate(context.Background(), sources, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, "10", r.Result)
+	assert.Equal(t, "2", r.Sources[0].Volume)
+	assert.Equal(t, `Unsupported JSONPath "$..price", only child names and array indexes can be used`, r.Sources[1].Error)
+}
+
+func TestGJSONPath(t *testing.T) {
+	for _, c := range []struct {
+		path, want string
+		valid      bool
+	}{
+		{"data.price", "data.price", true},
+		{"$.data.price", "data.price", true},
+		{"$.data[0].price", "data.0.price", true},
+		{`$["data"]['BTC.USD']`, `data.BTC\.USD`, true},
+		{"$[1][2]", "1.2", true},
+		{"$", "", false},
+		{"$..price", "", false},
+		{"$.data[*].price", "", false},
+		{"$.data[0:2]", "", false},
+		{"$.data[?(@.price)]", "", false},
+		{"$.data[0", "", false},
+	} {
+		t.Run(c.path, func(t *testing.T) {
+			p, err := gjsonPath(c.path)
+			assert.Equal(t, c.valid, err == nil)
+			assert.Equal(t, c.want, p)
+		})
+	}
+}
+
+func TestMethod_Valid(t *testing.T) {
+	assert.True(t, Median.Valid())
+	assert.False(t, Method("geometric").Valid())
+}
@@ -0,0 +1,147 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Method is the method of aggregating the values of the sources
+type Method string
+
+// Supported aggregation methods
+const (
+	Mean        Method = "mean"
+	Median      Method = "median"
+	Mode        Method = "mode"
+	VWAP        Method = "vwap"
+	TrimmedMean Method = "trimmedmean"
+	Min         Method = "min"
+	Max         Method = "max"
+)
+
+// Valid returns whether the aggregation method is supported
+func (m Method) Valid() bool {
+	switch m {
+	case Mean, Median, Mode, VWAP, TrimmedMean, Min, Max:
+		return true
+	}
+	return false
+}
+
+// DefaultTrimFraction is the fraction of values removed from each
+// end for a trimmed mean when no fraction is given
+const DefaultTrimFraction = 0.1
+
+// Value is a value given by a source, with the volume traded
+// at that value if it's to be weighted by volume
+type Value struct {
+	Value  *big.Rat
+	Volume *big.Rat
+}
+
+// Compute aggregates the values using the method. The trim fraction is the
+// fraction of values removed from each end for a trimmed mean, and values
+// must have a volume for a volume weighted mean.
+func Compute(method Method, values []Value, trim float64) (*big.Rat, error) {
+	if len(values) == 0 {
+		return nil, errors.New("No values to aggregate")
+	}
+	vs := make([]*big.Rat, len(values))
+	for i, v := range values {
+		vs[i] = v.Value
+	}
+
+	switch method {
+	case Mean, "":
+		return mean(vs), nil
+	case Median:
+		return median(vs), nil
+	case Mode:
+		return mode(vs), nil
+	case VWAP:
+		return vwap(values)
+	case TrimmedMean:
+		return trimmedMean(vs, trim)
+	case Min:
+		return sorted(vs)[0], nil
+	case Max:
+		s := sorted(vs)
+		return s[len(s)-1], nil
+	}
+	return nil, fmt.Errorf("Unsupported aggregation method: %s", method)
+}
+
+func mean(vs []*big.Rat) *big.Rat {
+	sum := new(big.Rat)
+	for _, v := range vs {
+		sum.Add(sum, v)
+	}
+	return sum.Quo(sum, new(big.Rat).SetInt64(int64(len(vs))))
+}
+
+func median(vs []*big.Rat) *big.Rat {
+	s := sorted(vs)
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+	return mean(s[len(s)/2-1 : len(s)/2+1])
+}
+
+// mode returns the most common value, being the lowest of any that are
+// equally common. If no value is repeated, the median is given.
+func mode(vs []*big.Rat) *big.Rat {
+	s := sorted(vs)
+	best, bestCount := s[0], 1
+	for i, count := 1, 1; i < len(s); i++ {
+		if s[i].Cmp(s[i-1]) == 0 {
+			count++
+		} else {
+			count = 1
+		}
+		if count > bestCount {
+			best, bestCount = s[i], count
+		}
+	}
+	if bestCount == 1 {
+		return median(vs)
+	}
+	return best
+}
+
+func vwap(values []Value) (*big.Rat, error) {
+	sum, volume := new(big.Rat), new(big.Rat)
+	for _, v := range values {
+		if v.Volume == nil {
+			return nil, errors.New("Volume weighted mean requires a volume for every value")
+		}
+		sum.Add(sum, new(big.Rat).Mul(v.Value, v.Volume))
+		volume.Add(volume, v.Volume)
+	}
+	if volume.Sign() <= 0 {
+		return nil, errors.New("Volume weighted mean requires a positive total volume")
+	}
+	return sum.Quo(sum, volume), nil
+}
+
+func trimmedMean(vs []*big.Rat, trim float64) (*big.Rat, error) {
+	if trim == 0 {
+		trim = DefaultTrimFraction
+	}
+	if trim < 0 || trim >= 0.5 {
+		return nil, fmt.Errorf("Invalid trim fraction: %v", trim)
+	}
+	s := sorted(vs)
+	n := int(float64(len(s)) * trim)
+	return mean(s[n : len(s)-n]), nil
+}
+
+// sorted returns a sorted copy of the values
+func sorted(vs []*big.Rat) []*big.Rat {
+	s := append([]*big.Rat{}, vs...)
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Cmp(s[j]) < 0
+	})
+	return s
+}
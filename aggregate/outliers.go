@@ -0,0 +1,70 @@
+package aggregate
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// OutlierMethod is the method of rejecting values that deviate
+// too far from the median of all the values
+type OutlierMethod string
+
+// Supported outlier rejection methods
+const (
+	// MAD rejects values further from the median than the threshold
+	// multiplied by the scaled median absolute deviation
+	MAD OutlierMethod = "mad"
+	// PercentDeviation rejects values that deviate from the median
+	// by more than the threshold as a percentage
+	PercentDeviation OutlierMethod = "percent"
+)
+
+// Default thresholds of the outlier rejection methods
+const (
+	DefaultMADThreshold     = 3.0
+	DefaultPercentThreshold = 10.0
+)
+
+// madScale scales the median absolute deviation to be consistent
+// with the standard deviation of normally distributed values
+var madScale = big.NewRat(14826, 10000)
+
+// outliers returns whether each value is an outlier
+func outliers(method OutlierMethod, vs []*big.Rat, threshold float64) ([]bool, error) {
+	out := make([]bool, len(vs))
+	if len(method) == 0 || len(vs) == 0 {
+		return out, nil
+	}
+
+	m := median(vs)
+	devs := make([]*big.Rat, len(vs))
+	for i, v := range vs {
+		devs[i] = new(big.Rat).Abs(new(big.Rat).Sub(v, m))
+	}
+
+	var limit *big.Rat
+	switch method {
+	case MAD:
+		if threshold == 0 {
+			threshold = DefaultMADThreshold
+		}
+		limit = new(big.Rat).Mul(median(devs), madScale)
+	case PercentDeviation:
+		if threshold == 0 {
+			threshold = DefaultPercentThreshold
+		}
+		limit = new(big.Rat).Quo(new(big.Rat).Abs(m), big.NewRat(100, 1))
+	default:
+		return nil, fmt.Errorf("Unsupported outlier method: %s", method)
+	}
+	t := new(big.Rat)
+	if _, ok := t.SetString(fmt.Sprint(threshold)); !ok || t.Sign() < 0 {
+		return nil, fmt.Errorf("Invalid outlier threshold: %v", threshold)
+	}
+	limit.Mul(limit, t)
+
+	for i, d := range devs {
+		out[i] = d.Cmp(limit) > 0
+	}
+	return out, nil
+}
@@ -0,0 +1,373 @@
+package bridges
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is returned by Bind when the request `data` doesn't
+// decode or pass validation, holding every violation found. The run is
+// errored as a bad request.
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Violation is a single field failing a validation rule
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "Invalid request data: " + strings.Join(msgs, "; ")
+}
+
+// StatusCode is the status code given for the errored result
+func (e *ValidationError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Bind decodes the request `data` into the struct pointer using its `json` tags,
+// then validates the fields using their `validate` tags, returning a
+// ValidationError with all the violations found. Supported rules, separated by
+// commas, are:
+//  - `required`: the field must be given and not be its zero value
+//  - `min=n`, `max=n`: the minimum and maximum of a number, or length of a string, slice or map
+//  - `oneof=a b c`: the value must be one of the space separated values
+//  - `url`: the string must be an absolute URL
+//  - `regexp=pattern`: the string must match the pattern, which must be the last rule
+// Nested structs, including those in slices, are validated recursively. Any
+// fields of the wrong type are given as violations along with the others.
+//
+// For example:
+//  type Request struct {
+//      Base   string   `json:"base" validate:"required,oneof=ETH BTC"`
+//      Quotes []string `json:"quotes" validate:"min=1,max=10"`
+//      API    string   `json:"api" validate:"url"`
+//  }
+func (h *Helper) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Bind requires a non-nil struct pointer")
+	}
+
+	raw := "{}"
+	if h.Data != nil && h.Data.Exists() {
+		raw = h.Data.Raw
+	}
+	// The fields that can be decoded still are on a type error,
+	// which are then validated along with the fields that weren't
+	var vs []Violation
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		var te *json.UnmarshalTypeError
+		if !errors.As(err, &te) {
+			return &ValidationError{Violations: []Violation{{Rule: "json", Message: err.Error()}}}
+		}
+		typeViolations([]byte(raw), rv.Elem().Type(), "", &vs)
+	}
+
+	var rvs []Violation
+	if err := validateStruct(rv.Elem(), "", &rvs); err != nil {
+		return err
+	}
+	for _, vl := range rvs {
+		if !hasTypeViolation(vs, vl.Field) {
+			vs = append(vs, vl)
+		}
+	}
+	if len(vs) > 0 {
+		return &ValidationError{Violations: vs}
+	}
+	return nil
+}
+
+// typeViolations decodes each field of the JSON object into the struct
+// type separately, appending a violation for every field of the wrong type
+func typeViolations(raw []byte, rt reflect.Type, prefix string, vs *[]Violation) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) != nil {
+		return
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := fieldName(f)
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && len(f.Tag.Get("json")) == 0 {
+			typeViolations(raw, f.Type, prefix, vs)
+			continue
+		}
+		if len(prefix) > 0 {
+			name = prefix + "." + name
+		}
+		if fr, ok := lookupField(obj, fieldName(f)); ok {
+			typeViolation(fr, f.Type, name, vs)
+		}
+	}
+}
+
+// typeViolation appends a violation if the JSON value can't be decoded into
+// the type, descending into structs and slices to name the nested fields
+func typeViolation(raw json.RawMessage, t reflect.Type, name string, vs *[]Violation) {
+	var te *json.UnmarshalTypeError
+	if err := json.Unmarshal(raw, reflect.New(t).Interface()); !errors.As(err, &te) {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) == nil {
+			typeViolations(raw, t, name, vs)
+			return
+		}
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) == nil {
+			for i, item := range items {
+				typeViolation(item, t.Elem(), fmt.Sprintf("%s[%d]", name, i), vs)
+			}
+			return
+		}
+	}
+	addViolation(vs, name, "type", "%s must be of type %s", name, te.Type)
+}
+
+// lookupField returns the value of the key in the object, matching
+// case-insensitively if there's no exact match as encoding/json does
+func lookupField(obj map[string]json.RawMessage, key string) (json.RawMessage, bool) {
+	if v, ok := obj[key]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// hasTypeViolation returns whether the field, or the field holding it,
+// is of the wrong type
+func hasTypeViolation(vs []Violation, field string) bool {
+	for _, v := range vs {
+		if v.Rule == "type" && (field == v.Field || strings.HasPrefix(field, v.Field+".") ||
+			strings.HasPrefix(field, v.Field+"[")) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateStruct validates each field of the struct, appending any violations.
+// An error is only returned for invalid validation tags.
+func validateStruct(rv reflect.Value, prefix string, vs *[]Violation) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		name := fieldName(f)
+		if name == "-" {
+			continue
+		}
+		if len(prefix) > 0 {
+			name = prefix + "." + name
+		}
+
+		fv := rv.Field(i)
+		if tag, ok := f.Tag.Lookup("validate"); ok {
+			if err := validateField(fv, name, tag, vs); err != nil {
+				return err
+			}
+		}
+		if err := validateNested(fv, name, vs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateNested validates any structs held by the value
+func validateNested(fv reflect.Value, name string, vs *[]Violation) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if !fv.IsNil() {
+			return validateNested(fv.Elem(), name, vs)
+		}
+	case reflect.Struct:
+		return validateStruct(fv, name, vs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := validateNested(fv.Index(i), fmt.Sprintf("%s[%d]", name, i), vs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateField checks the value against each rule in the tag
+func validateField(fv reflect.Value, name, tag string, vs *[]Violation) error {
+	for _, rule := range splitRules(tag) {
+		key, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			key, arg = rule[:i], rule[i+1:]
+		}
+
+		if key == "required" {
+			if isZero(fv) {
+				addViolation(vs, name, key, "%s is required", name)
+				return nil
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				return nil
+			}
+			fv = fv.Elem()
+		}
+
+		switch key {
+		case "min", "max":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return fmt.Errorf("Invalid %s rule on %s: %s", key, name, arg)
+			}
+			size, isLen, ok := measure(fv)
+			if !ok {
+				return fmt.Errorf("Invalid %s rule on %s: unsupported type", key, name)
+			}
+			if key == "min" && size < n {
+				if isLen {
+					addViolation(vs, name, key, "%s must have a length of at least %s", name, arg)
+				} else {
+					addViolation(vs, name, key, "%s must be at least %s", name, arg)
+				}
+			} else if key == "max" && size > n {
+				if isLen {
+					addViolation(vs, name, key, "%s must have a length of at most %s", name, arg)
+				} else {
+					addViolation(vs, name, key, "%s must be at most %s", name, arg)
+				}
+			}
+		case "oneof":
+			s := fmt.Sprint(fv.Interface())
+			if fv.Kind() == reflect.String && len(s) == 0 {
+				continue
+			}
+			var found bool
+			for _, o := range strings.Fields(arg) {
+				if s == o {
+					found = true
+					break
+				}
+			}
+			if !found {
+				addViolation(vs, name, key, "%s must be one of [%s]", name, arg)
+			}
+		case "url":
+			if fv.Kind() != reflect.String {
+				return fmt.Errorf("Invalid url rule on %s: unsupported type", name)
+			}
+			if s := fv.String(); len(s) > 0 {
+				if u, err := url.Parse(s); err != nil || !u.IsAbs() || len(u.Host) == 0 {
+					addViolation(vs, name, key, "%s must be a valid URL", name)
+				}
+			}
+		case "regexp":
+			if fv.Kind() != reflect.String {
+				return fmt.Errorf("Invalid regexp rule on %s: unsupported type", name)
+			}
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return fmt.Errorf("Invalid regexp rule on %s: %v", name, err)
+			}
+			if s := fv.String(); len(s) > 0 && !re.MatchString(s) {
+				addViolation(vs, name, key, "%s must match %s", name, arg)
+			}
+		default:
+			return fmt.Errorf("Unknown validation rule on %s: %s", name, key)
+		}
+	}
+	return nil
+}
+
+// splitRules splits the tag by commas, with a regexp rule taking
+// the remainder of the tag
+func splitRules(tag string) []string {
+	var rules []string
+	for len(tag) > 0 {
+		if strings.HasPrefix(tag, "regexp=") {
+			return append(rules, tag)
+		}
+		i := strings.Index(tag, ",")
+		if i < 0 {
+			return append(rules, tag)
+		}
+		rules = append(rules, tag[:i])
+		tag = tag[i+1:]
+	}
+	return rules
+}
+
+// measure returns the size of the value compared by min and max, being the
+// value of a number or the length of a string, slice or map
+func measure(fv reflect.Value) (float64, bool, bool) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), false, true
+	case reflect.String:
+		return float64(len([]rune(fv.String()))), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(fv.Len()), true, true
+	}
+	return 0, false, false
+}
+
+func isZero(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
+
+func fieldName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) > 0 {
+		return tag
+	}
+	return f.Name
+}
+
+func addViolation(vs *[]Violation, field, rule, format string, args ...interface{}) {
+	*vs = append(*vs, Violation{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
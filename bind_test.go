@@ -0,0 +1,128 @@
+package bridges
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type bindSource struct {
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"min=1,max=10"`
+}
+
+type bindRequest struct {
+	Base    string       `json:"base" validate:"required,oneof=ETH BTC"`
+	Quotes  []string     `json:"quotes" validate:"required,min=1,max=2"`
+	Address string       `json:"address" validate:"regexp=^0x[0-9a-fA-F]{40}$"`
+	Limit   *int         `json:"limit" validate:"max=100"`
+	Sources []bindSource `json:"sources"`
+}
+
+func TestHelper_Bind(t *testing.T) {
+	data, err := Parse([]byte(`{
+		"base": "ETH",
+		"quotes": ["USD"],
+		"address": "0x0000000000000000000000000000000000000001",
+		"sources": [{"url": "https://example.com", "weight": 2}]
+	}`))
+	assert.Nil(t, err)
+
+	var req bindRequest
+	assert.Nil(t, NewHelper(data).Bind(&req))
+	assert.Equal(t, "ETH", req.Base)
+	assert.Equal(t, []string{"USD"}, req.Quotes)
+	assert.Nil(t, req.Limit)
+	assert.Equal(t, "https://example.com", req.Sources[0].URL)
+}
+
+func TestHelper_Bind_Violations(t *testing.T) {
+	data, err := Parse([]byte(`{
+		"base": "XRP",
+		"quotes": ["USD", "EUR", "GBP"],
+		"address": "0x01",
+		"limit": 101,
+		"sources": [{"url": "example", "weight": 0}]
+	}`))
+	assert.Nil(t, err)
+
+	var req bindRequest
+	err = NewHelper(data).Bind(&req)
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, []Violation{
+		{Field: "base", Rule: "oneof", Message: "base must be one of [ETH BTC]"},
+		{Field: "quotes", Rule: "max", Message: "quotes must have a length of at most 2"},
+		{Field: "address", Rule: "regexp", Message: "address must match ^0x[0-9a-fA-F]{40}$"},
+		{Field: "limit", Rule: "max", Message: "limit must be at most 100"},
+		{Field: "sources[0].url", Rule: "url", Message: "sources[0].url must be a valid URL"},
+		{Field: "sources[0].weight", Rule: "min", Message: "sources[0].weight must be at least 1"},
+	}, ve.Violations)
+	assert.Equal(t, http.StatusBadRequest, ve.StatusCode())
+}
+
+func TestHelper_Bind_Errors(t *testing.T) {
+	var req bindRequest
+	err := NewHelper(nil).Bind(&req)
+	assert.EqualError(t, err, "Invalid request data: base is required; quotes is required")
+
+	data, err := Parse([]byte(`{"base": 1}`))
+	assert.Nil(t, err)
+	err = NewHelper(data).Bind(&req)
+	assert.EqualError(t, err, "Invalid request data: base must be of type string; quotes is required")
+
+	data, err = Parse([]byte(`{
+		"base": 1,
+		"quotes": ["USD", 2, "EUR"],
+		"limit": "ten",
+		"sources": [{"url": "https://example.com", "weight": "heavy"}, {"weight": 20}]
+	}`))
+	assert.Nil(t, err)
+	err = NewHelper(data).Bind(&req)
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.Equal(t, []Violation{
+		{Field: "base", Rule: "type", Message: "base must be of type string"},
+		{Field: "quotes[1]", Rule: "type", Message: "quotes[1] must be of type string"},
+		{Field: "limit", Rule: "type", Message: "limit must be of type int"},
+		{Field: "sources[0].weight", Rule: "type", Message: "sources[0].weight must be of type int"},
+		{Field: "quotes", Rule: "max", Message: "quotes must have a length of at most 2"},
+		{Field: "sources[1].url", Rule: "required", Message: "sources[1].url is required"},
+		{Field: "sources[1].weight", Rule: "max", Message: "sources[1].weight must be at most 10"},
+	}, ve.Violations)
+
+	assert.NotNil(t, NewHelper(nil).Bind(req))
+
+	var invalid struct {
+		Value string `validate:"unknown"`
+	}
+	assert.EqualError(t, NewHelper(nil).Bind(&invalid), "Unknown validation rule on Value: unknown")
+}
+
+type Bind struct{}
+
+func (b *Bind) Run(h *Helper) (interface{}, error) {
+	var req bindRequest
+	if err := h.Bind(&req); err != nil {
+		return nil, err
+	}
+	return map[string]string{"base": req.Base}, nil
+}
+
+func (b *Bind) Opts() *Opts {
+	return &Opts{Name: "Bind"}
+}
+
+func TestServer_Mux_Bind(t *testing.T) {
+	mux := NewServer(&Bind{}).Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{"id": "1234", "data": map[string]interface{}{"base": "XRP"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "errored", json.Get("status").String())
+	assert.Equal(t, "Invalid request data: base must be one of [ETH BTC]; quotes is required", json.Get("error").String())
+
+	code, json = postRun(t, mux, map[string]interface{}{"id": "1234", "data": map[string]interface{}{"base": "BTC", "quotes": []string{"USD"}}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "BTC", json.Get("data.base").String())
+}
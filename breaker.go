@@ -0,0 +1,188 @@
+package bridges
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the http call methods when the circuit breaker
+// for the host is open, without the call being made
+var ErrCircuitOpen = errors.New("Circuit breaker is open")
+
+// DefaultBreakerPolicy is the policy any zero fields of a given
+// BreakerPolicy fall back to
+var DefaultBreakerPolicy = BreakerPolicy{
+	FailureThreshold: 5,
+	OpenTimeout:      30 * time.Second,
+}
+
+// BreakerPolicy sets when the circuit breaker of an upstream host opens. Once open,
+// calls to the host fail fast until OpenTimeout has passed, where a single trial
+// call is let through to decide whether to close the breaker again.
+//
+// Failures are counted using the same rules as to whether a call is retried.
+type BreakerPolicy struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold int `json:"failureThreshold"`
+	// OpenTimeout is how long the breaker stays open before a trial call
+	OpenTimeout time.Duration `json:"openTimeout"`
+}
+
+func (bp *BreakerPolicy) withDefaults() BreakerPolicy {
+	p := *bp
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = DefaultBreakerPolicy.FailureThreshold
+	}
+	if p.OpenTimeout == 0 {
+		p.OpenTimeout = DefaultBreakerPolicy.OpenTimeout
+	}
+	return p
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (bs breakerState) String() string {
+	switch bs {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker is the circuit breaker state of a single host
+type breaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// breakers holds the circuit breakers indexed by host, shared between
+// the helpers of a server
+type breakers struct {
+	mu      sync.Mutex
+	hosts   map[string]*breaker
+	metrics *metrics
+}
+
+// defaultBreakers is used by any helper not created by a server
+var defaultBreakers = newBreakers(nil)
+
+func newBreakers(m *metrics) *breakers {
+	return &breakers{hosts: make(map[string]*breaker), metrics: m}
+}
+
+// allow returns whether a call to the host can be made
+func (bs *breakers) allow(host string, bp BreakerPolicy) bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b := bs.get(host)
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < bp.OpenTimeout {
+			return false
+		}
+		bs.transition(host, b, breakerHalfOpen)
+		b.trial = true
+		return true
+	case breakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the host's breaker with the outcome of a call
+func (bs *breakers) record(host string, bp BreakerPolicy, failed bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	b := bs.get(host)
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		bs.transition(host, b, breakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= bp.FailureThreshold {
+		b.openedAt = time.Now()
+		bs.transition(host, b, breakerOpen)
+	}
+}
+
+// release ends the half-open trial of the host's breaker, if any,
+// without recording an outcome
+func (bs *breakers) release(host string) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.get(host).trial = false
+}
+
+func (bs *breakers) get(host string) *breaker {
+	b, ok := bs.hosts[host]
+	if !ok {
+		b = &breaker{}
+		bs.hosts[host] = b
+	}
+	return b
+}
+
+func (bs *breakers) transition(host string, b *breaker, state breakerState) {
+	if b.state == state {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"host":  host,
+		"from":  b.state.String(),
+		"to":    state.String(),
+		"fails": b.failures,
+	}).Warn("Circuit breaker state changed")
+	b.state = state
+	bs.metrics.observeBreakerState(host, state)
+}
+
+// doWithBreaker performs a single attempt of the http call, guarded by
+// the circuit breaker of the host if one is set in the options
+func (h *Helper) doWithBreaker(req *http.Request, opts CallOpts, rp RetryPolicy) (*upstreamResponse, error) {
+	if opts.CircuitBreaker == nil {
+		return h.do(req, opts)
+	}
+	bs := h.breakers
+	if bs == nil {
+		bs = defaultBreakers
+	}
+	bp := opts.CircuitBreaker.withDefaults()
+	host := req.URL.Host
+
+	if !bs.allow(host, bp) {
+		return nil, fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+	}
+	resp, err := h.do(req, opts)
+	if req.Context().Err() != nil {
+		// Calls given up on by the caller say nothing about the host,
+		// unlike the http client timing out
+		bs.release(host)
+	} else {
+		bs.record(host, bp, err != nil && rp.retryable(err))
+	}
+	return resp, err
+}
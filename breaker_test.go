@@ -0,0 +1,127 @@
+package bridges
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHelper_HTTPCallWithOpts_CircuitBreaker(t *testing.T) {
+	var attempts, down int32 = 0, 1
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+
+	s := NewServer()
+	h := s.newHelper(context.Background(), &HelloWorld{}, nil)
+	opts := CallOpts{
+		CircuitBreaker: &BreakerPolicy{FailureThreshold: 2, OpenTimeout: 50 * time.Millisecond},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+		assert.EqualError(t, err, "Unexpected api status code: 500")
+	}
+	_, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	// Helpers share the server's breakers
+	_, err = s.newHelper(context.Background(), &HelloWorld{}, nil).HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&down, 0)
+	_, err = h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	_, err = h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.Nil(t, err)
+}
+
+func TestBreakers_HalfOpen(t *testing.T) {
+	bs := newBreakers(nil)
+	bp := BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Millisecond}
+
+	assert.True(t, bs.allow("host", bp))
+	bs.record("host", bp, true)
+	assert.False(t, bs.allow("host", bp))
+
+	time.Sleep(2 * time.Millisecond)
+	assert.True(t, bs.allow("host", bp))
+	// Only a single trial call is let through when half-open
+	assert.False(t, bs.allow("host", bp))
+
+	bs.record("host", bp, true)
+	assert.Equal(t, breakerOpen, bs.hosts["host"].state)
+}
+
+func TestHelper_HTTPCallWithOpts_CircuitBreakerCancelled(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	s := NewServer()
+	opts := CallOpts{
+		CircuitBreaker: &BreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Millisecond},
+	}
+	_, err := s.newHelper(context.Background(), &HelloWorld{}, nil).HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.NotNil(t, err)
+
+	// A call cancelled by the caller doesn't reset the failure count
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = s.newHelper(ctx, &HelloWorld{}, nil).HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.True(t, errors.Is(err, context.Canceled))
+	host := upstream.Listener.Addr().String()
+	assert.Equal(t, 1, s.breakers.hosts[host].failures)
+
+	// Nor closes a half-open breaker, only ending its trial
+	_, err = s.newHelper(context.Background(), &HelloWorld{}, nil).HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.NotNil(t, err)
+	assert.Equal(t, breakerOpen, s.breakers.hosts[host].state)
+	time.Sleep(2 * time.Millisecond)
+	_, err = s.newHelper(ctx, &HelloWorld{}, nil).HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, breakerHalfOpen, s.breakers.hosts[host].state)
+	assert.False(t, s.breakers.hosts[host].trial)
+}
+
+func TestHelper_HTTPCallWithOpts_CircuitBreakerClientTimeout(t *testing.T) {
+	var attempts int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+
+	s := NewServer()
+	s.HTTPClient = &http.Client{Timeout: 20 * time.Millisecond}
+	h := s.newHelper(context.Background(), &HelloWorld{}, nil)
+	opts := CallOpts{
+		Retry:          &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		CircuitBreaker: &BreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Minute},
+	}
+
+	// Timeouts of the http client are retried and recorded as failures,
+	// with the third attempt failing on the breaker opened by the first two
+	_, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.Equal(t, breakerOpen, s.breakers.hosts[upstream.Listener.Addr().String()].state)
+
+	_, err = h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
+	"golang.org/x/sync/singleflight"
 	"gopkg.in/guregu/null.v3"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -26,17 +32,57 @@ type Opts struct {
 	Name   string `json:"name"`
 	Path   string `json:"path"`
 	Lambda bool   `json:"Lambda"`
+
+	// Async marks the bridge as long running. The server will respond to the
+	// node as pending straight away, then PATCH the final result to the
+	// `responseURL` given in the request once the run has finished. Runs from
+	// Lambda proxy events are synchronous, responding with the result instead.
+	Async bool `json:"async"`
+	// AccessToken is the bridge's outgoing token as set on the node, used to
+	// authenticate the async callback to the `responseURL`.
+	AccessToken string `json:"-"`
+	// InboundAuth authenticates the requests received for this bridge,
+	// taking precedence over the server's InboundAuth.
+	InboundAuth InboundAuth `json:"-"`
+	// Timeout is the maximum duration of a run, overriding the server's
+	// Timeout. Zero means the server's Timeout is used.
+	Timeout time.Duration `json:"timeout"`
+	// HTTPClient is used for the bridge's upstream calls instead of
+	// the server's HTTPClient.
+	HTTPClient *http.Client `json:"-"`
+	// ResponseSchema is the format of the responses given to the node,
+	// overriding the server's ResponseSchema.
+	ResponseSchema ResponseSchema `json:"responseSchema"`
+	// MergeData deep merges the output of the bridge into the request `data`
+	// instead of replacing it, so input fields are passed along to later tasks.
+	// Output that isn't an object is set under the `result` key.
+	MergeData bool `json:"mergeData"`
+	// MergePrecedence decides which value is kept when merging keys
+	// that exist in both, defaulting to the bridge output.
+	MergePrecedence Precedence `json:"mergePrecedence"`
+	// Transformers are ran in order on the output of the bridge, such as
+	// to pick out a value, multiply it and ABI encode it.
+	Transformers []Transformer `json:"-"`
+	// DataTransformers enables the reserved `path`, `times` and `encode` keys
+	// in the request data, adding JSONParse, Multiply and Encode transformers
+	// after any set in Transformers.
+	DataTransformers bool `json:"dataTransformers"`
 }
 
 // Result represents a Chainlink JobRun
 type Result struct {
-	JobRunID  string      `json:"jobRunId"`
-	ID        string      `json:"id,omitempty"`
-	TaskRunID string      `json:"taskRunId,omitempty"`
-	Status    string      `json:"status"`
-	Error     null.String `json:"error"`
-	Pending   bool        `json:"pending"`
-	Data      *JSON       `json:"data"`
+	JobRunID    string      `json:"jobRunId"`
+	ID          string      `json:"id,omitempty"`
+	TaskRunID   string      `json:"taskRunId,omitempty"`
+	Status      string      `json:"status"`
+	Error       null.String `json:"error"`
+	Pending     bool        `json:"pending"`
+	Data        *JSON       `json:"data"`
+	ResponseURL string      `json:"responseURL,omitempty"`
+
+	// ProviderStatusCode is the status code of the last upstream call made
+	// by the bridge, given in the v2 response format.
+	ProviderStatusCode int `json:"-"`
 }
 
 // Based on https://github.com/smartcontractkit/chainlink/blob/master/core/store/models/common.go#L128
@@ -84,15 +130,24 @@ func (j *JSON) MarshalJSON() ([]byte, error) {
 	return []byte("{}"), nil
 }
 
-// SetCompleted marks a result as errored
+// SetErrored marks a result as errored
 func (r *Result) SetErrored(err error) {
 	r.Status = "errored"
+	r.Pending = false
 	r.Error = null.StringFrom(err.Error())
 }
 
 // SetCompleted marks a result as completed
 func (r *Result) SetCompleted() {
 	r.Status = "completed"
+	r.Pending = false
+}
+
+// SetPending marks a result as pending, signalling to the node that
+// the result will be given later via the `responseURL`
+func (r *Result) SetPending() {
+	r.Status = "pending"
+	r.Pending = true
 }
 
 // SetJobRunID sets the request's ID to the result's Job Run ID.
@@ -112,8 +167,64 @@ type Bridge interface {
 // Server holds pointers to the bridges indexed by their paths
 // and the bridge to be mounted in Lambda.
 type Server struct {
+	// InboundAuth authenticates requests received for any bridge
+	// that doesn't set its own in Opts.
+	InboundAuth InboundAuth
+	// Timeout is the default maximum duration of a run for bridges
+	// that don't set their own in Opts. Zero means no timeout.
+	Timeout time.Duration
+	// Addr is the TCP address the inbuilt http server listens on when ran.
+	Addr string
+	// ShutdownTimeout is the maximum duration given for in-flight requests
+	// and async runs to finish on shutdown, defaulting to 30 seconds.
+	ShutdownTimeout time.Duration
+	// DrainDelay is the duration readiness probes are failed for on shutdown
+	// before new connections stop being accepted, giving load balancers time
+	// to stop routing requests to the server. Zero means no delay.
+	DrainDelay time.Duration
+	// HealthCheckTimeout is the maximum duration given for bridge health
+	// checks on readiness probes, defaulting to 5 seconds.
+	HealthCheckTimeout time.Duration
+	// HTTPClient is shared by the helpers of all the bridges to make
+	// upstream calls, and used to send async results to the node.
+	HTTPClient *http.Client
+	// Cache stores upstream responses for calls made with a CacheTTL,
+	// defaulting to an in-memory LRU cache. Nil disables caching.
+	Cache Cache
+	// IdempotencyStore stores the results of job runs so retried requests
+	// are given the stored result. Nil disables idempotent runs.
+	IdempotencyStore IdempotencyStore
+	// ResponseSchema is the format of the responses given to the node for
+	// bridges that don't set their own in Opts, defaulting to SchemaLegacy.
+	ResponseSchema ResponseSchema
+	// PreciseNumbers decodes numbers in upstream responses into interfaces as
+	// json.Number instead of float64, so they're given in the result exactly
+	// as received without being rounded.
+	PreciseNumbers bool
+	// MaxBodySize is the maximum size of a request body in bytes,
+	// responding with 413 if exceeded. Zero means no limit.
+	MaxBodySize int64
+	// Runtime is the runtime the server is started in by Start,
+	// detected from the environment by DetectRuntime if empty.
+	Runtime string
+	// TLS serves the inbuilt http server over HTTPS if set.
+	TLS *TLSConfig
+
 	pathMap   map[string]Bridge
 	ldaBridge Bridge
+	overrides map[string]BridgeConfig
+	mux       http.Handler
+	muxOnce   sync.Once
+
+	metrics      *metrics
+	breakers     *breakers
+	group        *singleflight.Group
+	runs         singleflight.Group
+	pending      sync.WaitGroup
+	mu           sync.Mutex
+	srv          *http.Server
+	shuttingDown bool
+	closeOnce    sync.Once
 }
 
 // NewServer returns a new Server with the bridges
@@ -122,7 +233,9 @@ type Server struct {
 // for any new requests.
 //
 // If a bridge is passed in that has a duplicate path
-// then the last one with that path will be mounted.
+// then the last one with that path will be mounted. Bridges can't be
+// mounted on MetricsPath, HealthPath or ReadyPath, which the server
+// fails to start with.
 //
 // Any bridge with an empty path gets assigned "/" to avoid
 // panics on start.
@@ -142,30 +255,61 @@ func NewServer(bridges ...Bridge) *Server {
 			lda = b
 		}
 	}
+	m := newMetrics()
 	return &Server{
-		pathMap:   pm,
-		ldaBridge: lda,
+		HTTPClient: DefaultHTTPClient,
+		Cache:      NewLRUCache(DefaultCacheSize),
+		pathMap:    pm,
+		ldaBridge:  lda,
+		metrics:    m,
+		breakers:   newBreakers(m),
+		group:      &singleflight.Group{},
 	}
 }
 
 // Start the bridge server. Routing on how the server is started is determined which
-// platform is specified by the end user. Currently supporting:
+// platform is specified by the end user, or detected from the environment by
+// DetectRuntime if Runtime isn't set. Currently supporting:
 //  - Inbuilt http (default)
 //  - AWS Lambda (env LAMBDA=1)
+//  - GCP Functions, including Pub/Sub-triggered functions (env FUNCTION_TARGET)
+//  - CloudEvents delivered over http
 //
-// Port only has to be passed in if the inbuilt HTTP server is being used.
+// Port only has to be passed in if the inbuilt HTTP server is being used and
+// Addr isn't already set, such as by NewServerWithConfig.
 //
 // If the inbuilt http server is being used, bridges can specify many external adaptors
 // as long if exclusive paths are given.
 //
-// If multiple adaptors are included with Lambda/gcp enabled, then the first bridge that
-// has it enabled will be given as the Handler.
+// In Lambda, API Gateway and ALB proxy events are routed by path to any of the bridges.
+// Any other event is given to the first bridge that has Lambda enabled.
+//
+// The inbuilt http server is gracefully shut down on SIGINT or SIGTERM.
 func (s *Server) Start(port int) {
-	if len(os.Getenv("LAMBDA")) > 0 {
-		lambda.Start(s.Lambda)
-	} else {
-		logrus.WithField("port", port).Info("Starting the bridge server")
-		logrus.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), s.Mux()))
+	name := s.Runtime
+	if len(name) == 0 {
+		name = DetectRuntime()
+	}
+	rt, err := NewRuntime(name)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sc
+		logrus.WithField("signal", sig).Info("Shutting down the bridge server")
+		cancel()
+	}()
+
+	if port != 0 || len(s.Addr) == 0 {
+		s.Addr = fmt.Sprintf(":%d", port)
+	}
+	logrus.WithField("runtime", name).Info("Starting the bridge server")
+	if err := rt.Start(ctx, s); err != nil {
+		logrus.Fatal(err)
 	}
 }
 
@@ -174,74 +318,321 @@ func (s *Server) Start(port int) {
 func (s *Server) Mux() http.Handler {
 	mux := http.NewServeMux()
 	for p, b := range s.pathMap {
+		if reservedPath(p) {
+			logrus.Error(errReservedPath(b, p))
+			continue
+		}
 		logrus.WithField("path", p).WithField("bridge", b.Opts().Name).Info("Registering bridge")
 		mux.HandleFunc(p, s.Handler)
 	}
+	mux.Handle(MetricsPath, s.MetricsHandler())
+	mux.HandleFunc(HealthPath, s.HealthHandler)
+	mux.HandleFunc(ReadyPath, s.ReadyHandler)
 	return mux
 }
 
+func reservedPath(p string) bool {
+	return p == MetricsPath || p == HealthPath || p == ReadyPath
+}
+
+func errReservedPath(b Bridge, p string) error {
+	return fmt.Errorf("Bridge %s can't be mounted on %s, which is reserved by the server", b.Opts().Name, p)
+}
+
+// checkPaths returns an error if any bridge is mounted on a reserved path
+func (s *Server) checkPaths() error {
+	for p, b := range s.pathMap {
+		if reservedPath(p) {
+			return errReservedPath(b, p)
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the request with the Mux, so the server can be given as
+// the handler of GCP Functions or any other http server
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.muxOnce.Do(func() {
+		s.mux = s.Mux()
+	})
+	s.mux.ServeHTTP(w, r)
+}
+
 // Hander is of http.Handler type, receiving any inbound requests from the HTTP server
 // when the bridge is ran local
 func (s *Server) Handler(w http.ResponseWriter, r *http.Request) {
 	var rt Result
+	var code int
+	var name string
+	var schema ResponseSchema
 	start := time.Now()
-	cc := make(chan int, 1)
+	done := func(string) {}
 
 	defer func() {
-		code := <-cc
+		if p := recover(); p != nil {
+			code = http.StatusInternalServerError
+			rt.SetErrored(s.recovered(p, name))
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(code)
-		if err := json.NewEncoder(w).Encode(&rt); err != nil {
+		if err := json.NewEncoder(w).Encode(s.response(resolveSchema(schema, &rt), &rt, code)); err != nil {
 			logrus.Errorf("Failed to encode response: %v", err)
 		}
 		s.logRequest(r, code, start)
+		done(rt.Status)
 	}()
 
+	path := "unknown"
+	b, ok := s.pathMap[s.path(r)]
+	if ok {
+		name, path = b.Opts().Name, s.path(r)
+	}
+	schema = s.responseSchema(b)
+	done = s.metrics.startRequest(name, path)
+
 	if r.Method != http.MethodPost {
-		cc <- http.StatusBadRequest
+		code = http.StatusBadRequest
 		rt.SetErrored(errors.New("Invalid request"))
 		return
 	}
-	if b, err := ioutil.ReadAll(r.Body); err != nil {
-		cc <- http.StatusInternalServerError
+	if body, err := s.readBody(r); err != nil {
+		code = http.StatusInternalServerError
+		if err == errBodyTooLarge {
+			code = http.StatusRequestEntityTooLarge
+		}
+		rt.SetErrored(err)
+		return
+	} else if err = s.authenticate(r, body); err != nil {
+		code = http.StatusUnauthorized
 		rt.SetErrored(err)
 		return
-	} else if err = json.Unmarshal(b, &rt); err != nil {
-		cc <- http.StatusBadRequest
+	} else if err = json.Unmarshal(body, &rt); err != nil {
+		code = http.StatusBadRequest
 		rt.SetErrored(err)
 		return
 	}
 
+	schema = resolveSchema(schema, &rt)
 	rt.SetJobRunID()
 
-	if b, ok := s.pathMap[s.path(r)]; !ok {
-		cc <- http.StatusBadRequest
+	if !ok {
+		code = http.StatusBadRequest
 		rt.SetErrored(errors.New("Invalid path"))
-	} else if obj, err := b.Run(NewHelper(rt.Data)); err != nil {
-		cc <- http.StatusInternalServerError
-		rt.SetErrored(err)
-	} else if data, err := ParseInterface(obj); err != nil {
-		cc <- http.StatusInternalServerError
-		rt.SetErrored(err)
 	} else {
-		rt.Data = data
-		rt.SetCompleted()
-		cc <- http.StatusOK
+		code = s.runIdempotent(b, &rt, func(rt *Result) int {
+			// Lambda is frozen once a proxy event is responded to, so the
+			// run can't be left in the background and is responded with
+			if s.opts(b).Async && len(rt.ResponseURL) > 0 && !isProxyEvent(r.Context()) {
+				s.runAsync(b, *rt)
+				rt.SetPending()
+				return http.StatusOK
+			}
+			return s.run(r.Context(), b, rt)
+		})
 	}
 }
 
+// Lambda is the handler for AWS Lambda, running the Lambda bridge
+// with the given result
 func (s *Server) Lambda(r *Result) (interface{}, error) {
+	return s.LambdaWithContext(context.Background(), r)
+}
+
+// LambdaWithContext mirrors Lambda, with the run being cancelled
+// when the given context is done, such as the Lambda deadline
+func (s *Server) LambdaWithContext(ctx context.Context, r *Result) (interface{}, error) {
+	if s.ldaBridge == nil {
+		return nil, errNoLambdaBridge
+	}
+	opts := s.ldaBridge.Opts()
+	done := s.metrics.startRequest(opts.Name, opts.Path)
+	defer func() {
+		done(r.Status)
+	}()
+
+	schema := resolveSchema(s.responseSchema(s.ldaBridge), r)
 	r.SetJobRunID()
+	code := s.runIdempotent(s.ldaBridge, r, func(r *Result) int {
+		return s.run(ctx, s.ldaBridge, r)
+	})
+	return s.response(schema, r, code), nil
+}
+
+// run calls the bridge with the request data, setting the outcome on the
+// result and returning the http status code to respond with.
+//
+// If the bridge has a timeout, the run is given up on once it's exceeded
+// and the result is errored with a gateway timeout status code.
+func (s *Server) run(ctx context.Context, b Bridge, rt *Result) int {
+	if t := s.timeout(b); t > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t)
+		defer cancel()
+	}
+
+	type output struct {
+		obj interface{}
+		err error
+	}
+	h := s.newHelper(ctx, b, rt.Data)
+	oc := make(chan output, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				oc <- output{err: s.recovered(p, b.Opts().Name)}
+			}
+		}()
+		obj, err := b.Run(h)
+		oc <- output{obj, err}
+	}()
 
-	if obj, err := s.ldaBridge.Run(NewHelper(r.Data)); err != nil {
-		r.SetErrored(err)
-	} else if data, err := ParseInterface(obj); err != nil {
-		r.SetErrored(err)
+	var out output
+	select {
+	case out = <-oc:
+	case <-ctx.Done():
+		out.err = ctx.Err()
+	}
+	rt.ProviderStatusCode = h.ProviderStatusCode()
+
+	if out.err != nil && ctx.Err() == context.DeadlineExceeded {
+		rt.SetErrored(errors.New("Bridge run timed out"))
+		return http.StatusGatewayTimeout
+	} else if out.err != nil {
+		rt.SetErrored(out.err)
+		return errorStatusCode(out.err)
+	} else if data, err := s.output(b, rt.Data, out.obj); err != nil {
+		rt.SetErrored(err)
+		return errorStatusCode(err)
 	} else {
-		r.SetCompleted()
-		r.Data = data
+		rt.Data = data
+		rt.SetCompleted()
+		return http.StatusOK
+	}
+}
+
+// output returns the data of the result from the bridge output, ran through
+// any transformers and merged into the request data if set in the bridge options
+func (s *Server) output(b Bridge, data *JSON, obj interface{}) (*JSON, error) {
+	out, err := ParseInterface(obj)
+	if err != nil {
+		return nil, err
+	}
+	if out, err = s.transform(b, data, out); err != nil {
+		return nil, err
+	}
+	if opts := b.Opts(); opts.MergeData {
+		return mergeData(data, out, opts.MergePrecedence)
 	}
-	return r, nil
+	return out, nil
+}
+
+// runAsync runs the bridge in the background, sending the final result
+// to the node once finished
+func (s *Server) runAsync(b Bridge, rt Result) {
+	key, idempotent := s.idempotencyKey(b, &rt)
+	if idempotent {
+		p := rt
+		p.SetPending()
+		s.storeResult(key, &StoredResult{Code: http.StatusOK, Result: p})
+	}
+
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		code := s.run(context.Background(), b, &rt)
+		if idempotent {
+			s.storeResult(key, &StoredResult{Code: code, Result: rt})
+		}
+		if err := s.callback(b, &rt, code); err != nil {
+			logrus.WithField("jobRunId", rt.JobRunID).Errorf("Failed to send async result: %v", err)
+		}
+	}()
+}
+
+// StatusCoder can be implemented by errors returned from a bridge run to set the
+// status code of the errored result, such as a bad request for invalid input.
+// Any other errors are given an internal server error status code.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+func errorStatusCode(err error) int {
+	var sc StatusCoder
+	if errors.As(err, &sc) && sc.StatusCode() >= 400 {
+		return sc.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// recovered logs and counts a recovered panic with its stack trace,
+// returning the error to set on the result
+func (s *Server) recovered(p interface{}, bridge string) error {
+	s.metrics.observePanic(bridge)
+	logrus.WithFields(logrus.Fields{
+		"bridge": bridge,
+		"stack":  string(debug.Stack()),
+	}).Errorf("Recovered from panic: %v", p)
+	return fmt.Errorf("Bridge run panicked: %v", p)
+}
+
+// newHelper returns a Helper for a run of the bridge, sharing the server's
+// http client, cache, metrics, circuit breakers and in-flight calls
+func (s *Server) newHelper(ctx context.Context, b Bridge, data *JSON) *Helper {
+	h := NewHelperWithContext(ctx, data)
+	if c := b.Opts().HTTPClient; c != nil {
+		h.httpClient = c
+	} else if s.HTTPClient != nil {
+		h.httpClient = s.HTTPClient
+	}
+	h.metrics = s.metrics
+	h.breakers = s.breakers
+	h.cache = s.Cache
+	h.group = s.group
+	h.preciseNumbers = s.PreciseNumbers
+	return h
+}
+
+// timeout returns the maximum duration of a run for the bridge
+func (s *Server) timeout(b Bridge) time.Duration {
+	if t := s.opts(b).Timeout; t > 0 {
+		return t
+	}
+	return s.Timeout
+}
+
+func (s *Server) httpClient() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return DefaultHTTPClient
+}
+
+// callback sends the result of an async run to the node's `responseURL`,
+// using the bridge's access token for authentication
+func (s *Server) callback(br Bridge, rt *Result, code int) error {
+	opts := s.opts(br)
+	b, err := json.Marshal(s.response(resolveSchema(s.responseSchema(br), rt), rt, code))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPatch, rt.ResponseURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(opts.AccessToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+opts.AccessToken)
+	}
+
+	resp, err := s.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Unexpected node status code: %d", resp.StatusCode)
+	}
+	return nil
 }
 
 func (s *Server) logRequest(r *http.Request, code int, start time.Time) {
@@ -256,6 +647,20 @@ func (s *Server) logRequest(r *http.Request, code int, start time.Time) {
 	}).Info("Bridge request")
 }
 
+var errBodyTooLarge = errors.New("Request body too large")
+
+// readBody reads the request body, erroring if it exceeds MaxBodySize
+func (s *Server) readBody(r *http.Request) ([]byte, error) {
+	if s.MaxBodySize <= 0 {
+		return ioutil.ReadAll(r.Body)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, s.MaxBodySize+1))
+	if err == nil && int64(len(b)) > s.MaxBodySize {
+		return nil, errBodyTooLarge
+	}
+	return b, err
+}
+
 // Transformative logic to prepare the path, as if it's empty, it needs
 // setting to the root "/" path
 func (s *Server) path(r *http.Request) string {
@@ -272,23 +677,92 @@ func (s *Server) path(r *http.Request) string {
 type Helper struct {
 	Data *JSON
 
-	httpClient http.Client
+	ctx        context.Context
+	httpClient *http.Client
+	metrics    *metrics
+	breakers   *breakers
+	cache      Cache
+	group      *singleflight.Group
+
+	preciseNumbers     bool
+	providerStatusCode int32
 }
 
+// NewHelper returns a Helper for the given request data
 func NewHelper(data *JSON) *Helper {
-	return &Helper{Data: data, httpClient: http.Client{}}
+	return NewHelperWithContext(context.Background(), data)
 }
 
-// GetIntParam gets the string value of a key in the `data` JSON object that is
+// NewHelperWithContext returns a Helper for the given request data, where
+// any http calls made through it are cancelled once the context is done
+func NewHelperWithContext(ctx context.Context, data *JSON) *Helper {
+	return &Helper{Data: data, ctx: ctx, httpClient: DefaultHTTPClient}
+}
+
+// WithPreciseNumbers sets whether numbers in responses are decoded into
+// interfaces as json.Number instead of float64
+func (h *Helper) WithPreciseNumbers(precise bool) *Helper {
+	h.preciseNumbers = precise
+	return h
+}
+
+// WithHTTPClient sets the http client the helper makes calls with,
+// such as one with a stubbed transport in tests
+func (h *Helper) WithHTTPClient(c *http.Client) *Helper {
+	h.httpClient = c
+	return h
+}
+
+// Context returns the context of the run, which is done when the node
+// has given up on the request or the bridge's timeout has been exceeded
+func (h *Helper) Context() context.Context {
+	if h.ctx == nil {
+		return context.Background()
+	}
+	return h.ctx
+}
+
+// withContext returns a context that is done when either the given context
+// or the context of the run is done
+func (h *Helper) withContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if rc := h.Context(); rc.Done() != nil && rc != ctx {
+		go func() {
+			select {
+			case <-rc.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// ProviderStatusCode returns the status code of the last upstream call made
+// through the helper, or zero if none have responded
+func (h *Helper) ProviderStatusCode() int {
+	return int(atomic.LoadInt32(&h.providerStatusCode))
+}
+
+// setProviderStatusCode sets the status code of the last upstream response,
+// including those shared from cached or deduplicated calls
+func (h *Helper) setProviderStatusCode(code int) {
+	if code > 0 {
+		atomic.StoreInt32(&h.providerStatusCode, int32(code))
+	}
+}
+
+// GetParam gets the string value of a key in the `data` JSON object that is
 // given on request by the Chainlink node
 func (h *Helper) GetParam(key string) string {
-	return h.Data.Get(key).String()
+	return h.GetParamOrDefault(key, "")
 }
 
 // GetIntParam gets the int64 value of a key in the `data` JSON object that is
 // given on request by the Chainlink node
 func (h *Helper) GetIntParam(key string) int64 {
-	return h.Data.Get(key).Int()
+	r, _ := h.param(key)
+	return r.Int()
 }
 
 // CallOpts are the options given into a http call method
@@ -297,12 +771,18 @@ type CallOpts struct {
 	Query            map[string]interface{} `json:"query"`
 	QueryPassthrough bool                   `json:"queryPassthrough"`
 	Body             string                 `json:"body"`
+	Headers          map[string]string      `json:"headers"`
 	ExpectedCode     int                    `json:"expectedCode"`
+	Retry            *RetryPolicy           `json:"retry"`
+	CircuitBreaker   *BreakerPolicy         `json:"circuitBreaker"`
+	CacheTTL         time.Duration          `json:"cacheTTL"`
+	StaleIfError     time.Duration          `json:"staleIfError"`
+	Deduplicate      bool                   `json:"deduplicate"`
 }
 
 // HTTPCall performs a basic http call with no options
 func (h *Helper) HTTPCall(method, url string, obj interface{}) error {
-	return h.HTTPCallWithContext(context.Background(), method, url, obj)
+	return h.HTTPCallWithContext(h.Context(), method, url, obj)
 }
 
 func (h *Helper) HTTPCallWithContext(ctx context.Context, method, url string, obj interface{}) error {
@@ -312,13 +792,13 @@ func (h *Helper) HTTPCallWithContext(ctx context.Context, method, url string, ob
 // HTTPCallWithOpts mirrors HTTPCallRawWithOpts bar the returning byte body is unmarshalled into
 // a given object pointer
 func (h *Helper) HTTPCallWithOpts(method, url string, obj interface{}, opts CallOpts) error {
-	return h.HTTPCallWithOptsWithContext(context.Background(), method, url, obj, opts)
+	return h.HTTPCallWithOptsWithContext(h.Context(), method, url, obj, opts)
 }
 
 func (h *Helper) HTTPCallWithOptsWithContext(ctx context.Context, method, url string, obj interface{}, opts CallOpts) error {
 	if b, err := h.HTTPCallRawWithOptsWithContext(ctx, method, url, opts); err != nil {
 		return err
-	} else if err := json.Unmarshal(b, obj); err != nil {
+	} else if err := h.unmarshal(b, obj); err != nil {
 		return err
 	}
 	return nil
@@ -330,18 +810,29 @@ func (h *Helper) HTTPCallWithOptsWithContext(ctx context.Context, method, url st
 // 	- Query parameters via `opts.Query`
 //  - Passthrough through all json keys within the request `data` object via `opts.QueryPassthrough`
 //  - Pass in a body to send with the request via `opts.Body`
+//  - Set headers on the request via `opts.Headers`
 //  - Send in post form kv via `opts.PostForm`
 //  - Return an error if the returning http status code is different to `opts.ExpectedCode`
+//  - Retry failed calls with backoff via `opts.Retry`
+//  - Fail fast when the upstream host is down via `opts.CircuitBreaker`
+//  - Cache the response for `opts.CacheTTL`, falling back to it for `opts.StaleIfError` if the call fails
+//  - Collapse identical in-flight calls into a single upstream request via `opts.Deduplicate`
 func (h *Helper) HTTPCallRawWithOpts(method, url string, opts CallOpts) ([]byte, error) {
-	return h.HTTPCallRawWithOptsWithContext(context.Background(), method, url, opts)
+	return h.HTTPCallRawWithOptsWithContext(h.Context(), method, url, opts)
 }
 
 func (h *Helper) HTTPCallRawWithOptsWithContext(ctx context.Context, method, url string, opts CallOpts) ([]byte, error) {
+	ctx, cancel := h.withContext(ctx)
+	defer cancel()
+
 	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader([]byte(opts.Body)))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
 
 	q := req.URL.Query()
 	if opts.QueryPassthrough {
@@ -358,18 +849,49 @@ func (h *Helper) HTTPCallRawWithOptsWithContext(ctx context.Context, method, url
 		opts.Auth.Authenticate(req)
 	}
 
-	if resp, err := h.httpClient.Do(req); err != nil {
+	return h.doWithCache(req, opts)
+}
+
+// upstreamResponse is the response of a successful http call
+type upstreamResponse struct {
+	Body       []byte
+	Header     http.Header
+	StatusCode int
+}
+
+// do performs a single attempt of the http call, returning a statusCodeError
+// if the response status code isn't what's expected
+func (h *Helper) do(req *http.Request, opts CallOpts) (*upstreamResponse, error) {
+	start := time.Now()
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		h.metrics.observeUpstream(req.URL.Host, 0, time.Since(start))
 		return nil, err
-	} else if b, err := ioutil.ReadAll(resp.Body); err != nil {
+	}
+	defer resp.Body.Close()
+	h.metrics.observeUpstream(req.URL.Host, resp.StatusCode, time.Since(start))
+	h.setProviderStatusCode(resp.StatusCode)
+
+	if b, err := ioutil.ReadAll(resp.Body); err != nil {
 		return nil, err
 	} else if (opts.ExpectedCode != 0 && resp.StatusCode != opts.ExpectedCode) ||
 		opts.ExpectedCode == 0 && resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Unexpected api status code: %d", resp.StatusCode)
+		return nil, &statusCodeError{Code: resp.StatusCode, Header: resp.Header}
 	} else {
-		return b, nil
+		return &upstreamResponse{Body: b, Header: resp.Header, StatusCode: resp.StatusCode}, nil
 	}
 }
 
+// statusCodeError is given when an api responds with an unexpected status code
+type statusCodeError struct {
+	Code   int
+	Header http.Header
+}
+
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("Unexpected api status code: %d", e.Code)
+}
+
 // Auth is the generic interface for how the client passes in their
 // API key for authentication
 type Auth interface {
@@ -346,3 +346,307 @@ func TestServer_Lambda_Timeout(t *testing.T) {
 	diff := time.Since(start)
 	assert.Less(t, int64(diff), int64(2*time.Second))
 }
+
+type AsyncHelloWorld struct{}
+
+func (ahw *AsyncHelloWorld) Run(h *Helper) (interface{}, error) {
+	return map[string]string{"key": "hello world"}, nil
+}
+
+func (ahw *AsyncHelloWorld) Opts() *Opts {
+	return &Opts{
+		Async:       true,
+		AccessToken: "token",
+	}
+}
+
+func TestServer_Mux_Async(t *testing.T) {
+	results := make(chan *JSON, 1)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "/v2/runs/1234", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		json, err := Parse(body)
+		assert.Nil(t, err)
+		results <- json
+	}))
+	defer node.Close()
+
+	mux := NewServer(&AsyncHelloWorld{}).Mux()
+
+	p := map[string]interface{}{
+		"id":          "1234",
+		"responseURL": node.URL + "/v2/runs/1234",
+	}
+	pb, err := json.Marshal(p)
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(pb))
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1234", json.Get("jobRunId").String())
+	assert.Equal(t, "pending", json.Get("status").String())
+	assert.True(t, json.Get("pending").Bool())
+
+	select {
+	case json := <-results:
+		assert.Equal(t, "1234", json.Get("jobRunId").String())
+		assert.Equal(t, "completed", json.Get("status").String())
+		assert.False(t, json.Get("pending").Bool())
+		assert.Equal(t, "hello world", json.Get("data.key").String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the async callback")
+	}
+}
+
+func TestServer_Mux_AsyncNoResponseURL(t *testing.T) {
+	mux := NewServer(&AsyncHelloWorld{}).Mux()
+
+	pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(pb))
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "completed", json.Get("status").String())
+	assert.Equal(t, "hello world", json.Get("data.key").String())
+}
+
+type SlowUpstream struct {
+	url     string
+	timeout time.Duration
+}
+
+func (su *SlowUpstream) Run(h *Helper) (interface{}, error) {
+	var r interface{}
+	err := h.HTTPCall(http.MethodGet, su.url, &r)
+	return r, err
+}
+
+func (su *SlowUpstream) Opts() *Opts {
+	return &Opts{
+		Name:    "SlowUpstream",
+		Lambda:  true,
+		Timeout: su.timeout,
+	}
+}
+
+func TestServer_Mux_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer upstream.Close()
+	defer close(done)
+
+	tests := []struct {
+		name   string
+		bridge time.Duration
+		server time.Duration
+	}{
+		{"bridge timeout", 100 * time.Millisecond, 0},
+		{"server timeout", 0, 100 * time.Millisecond},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewServer(&SlowUpstream{url: upstream.URL, timeout: test.bridge})
+			s.Timeout = test.server
+			mux := s.Mux()
+
+			pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+			assert.Nil(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(pb))
+			assert.Nil(t, err)
+			rr := httptest.NewRecorder()
+
+			start := time.Now()
+			mux.ServeHTTP(rr, req)
+			assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+			assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+
+			body, err := ioutil.ReadAll(rr.Body)
+			assert.Nil(t, err)
+			json, err := Parse(body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, "errored", json.Get("status").String())
+			assert.Equal(t, "Bridge run timed out", json.Get("error").String())
+		})
+	}
+}
+
+func TestServer_LambdaWithContext_Deadline(t *testing.T) {
+	done := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer upstream.Close()
+	defer close(done)
+
+	s := NewServer(&SlowUpstream{url: upstream.URL})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	r := &Result{}
+	r.ID = "1234"
+
+	start := time.Now()
+	obj, err := s.LambdaWithContext(ctx, r)
+	assert.Nil(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+
+	json, err := ParseInterface(obj)
+	assert.Nil(t, err)
+	assert.Equal(t, "errored", json.Get("status").String())
+}
+
+func TestHelper_HTTPCallWithContext_RunCancelled(t *testing.T) {
+	done := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer upstream.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	h := NewHelperWithContext(ctx, nil)
+
+	var r interface{}
+	start := time.Now()
+	err := h.HTTPCallWithContext(context.Background(), http.MethodGet, upstream.URL, &r)
+	assert.NotNil(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+type Panic struct{}
+
+func (p *Panic) Run(h *Helper) (interface{}, error) {
+	panic("bad input")
+}
+
+func (p *Panic) Opts() *Opts {
+	return &Opts{
+		Name:   "Panic",
+		Lambda: true,
+	}
+}
+
+func TestServer_Mux_Panic(t *testing.T) {
+	mux := NewServer(&Panic{}).Mux()
+
+	pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(pb))
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "1234", json.Get("jobRunId").String())
+	assert.Equal(t, "errored", json.Get("status").String())
+	assert.Equal(t, "Bridge run panicked: bad input", json.Get("error").String())
+}
+
+func TestServer_Lambda_Panic(t *testing.T) {
+	s := NewServer(&Panic{})
+
+	r := &Result{}
+	r.ID = "1234"
+
+	obj, err := s.Lambda(r)
+	assert.Nil(t, err)
+	json, err := ParseInterface(obj)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "errored", json.Get("status").String())
+	assert.Equal(t, "Bridge run panicked: bad input", json.Get("error").String())
+}
+
+type PanicOpts struct {
+	HelloWorld
+}
+
+func (po *PanicOpts) Opts() *Opts {
+	panic("bad opts")
+}
+
+func TestServer_Handler_Panic(t *testing.T) {
+	s := &Server{pathMap: map[string]Bridge{"/": &PanicOpts{}}}
+
+	pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, "/", bytes.NewReader(pb))
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+
+	s.Handler(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "errored", json.Get("status").String())
+}
+
+type ReservedPath struct {
+	HelloWorld
+}
+
+func (rp *ReservedPath) Opts() *Opts {
+	return &Opts{Name: "ReservedPath", Path: HealthPath}
+}
+
+func TestServer_ReservedPath(t *testing.T) {
+	s := NewServer(&ReservedPath{})
+	s.Addr = "127.0.0.1:0"
+	assert.EqualError(t, s.Run(context.Background()), "Bridge ReservedPath can't be mounted on /health, which is reserved by the server")
+
+	_, err := NewServerWithConfig(&Config{}, &ReservedPath{})
+	assert.NotNil(t, err)
+
+	// The built-in endpoint is served instead of panicking on registration
+	rr := httptest.NewRecorder()
+	s.Mux().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, HealthPath, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
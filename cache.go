@@ -0,0 +1,208 @@
+package bridges
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of responses held by the server's
+// cache when none is given
+const DefaultCacheSize = 1000
+
+// CacheEntry is a cached upstream response body
+type CacheEntry struct {
+	Body []byte `json:"body"`
+	// StatusCode is the status code the upstream responded with
+	StatusCode int `json:"statusCode,omitempty"`
+	// ExpiresAt is when the entry is no longer fresh
+	ExpiresAt time.Time `json:"expiresAt"`
+	// StaleUntil is when the entry can no longer be used as a fallback
+	// for a failed call
+	StaleUntil time.Time `json:"staleUntil"`
+}
+
+// Cache is the generic interface for storing upstream responses, allowing
+// it to be backed by stores such as Redis. Implementations must be safe for
+// concurrent use and can drop any entry once past its StaleUntil.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, e *CacheEntry)
+}
+
+// LRUCache is the in-memory Cache implementation, evicting the least
+// recently used entries once full
+type LRUCache struct {
+	size  int
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCache returns an LRUCache holding up to the given number of entries
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &LRUCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get returns the entry for the key, if it exists and isn't past StaleUntil
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruItem).entry
+	if time.Now().After(e.StaleUntil) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+// Set stores the entry for the key, evicting the least recently used
+// entry if the cache is full
+func (c *LRUCache) Set(key string, e *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).entry = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, entry: e})
+	if c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*lruItem).key)
+	}
+}
+
+// Len returns the number of entries in the cache
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// WithCache sets the cache the helper stores upstream responses in
+// when a call has a CacheTTL
+func (h *Helper) WithCache(c Cache) *Helper {
+	h.cache = c
+	return h
+}
+
+// doWithCache performs the http call, returning the cached response if
+// it's still fresh. If the call fails, a stale response is returned
+// when it's within the StaleIfError window.
+func (h *Helper) doWithCache(req *http.Request, opts CallOpts) ([]byte, error) {
+	if opts.CacheTTL <= 0 || h.cache == nil {
+		resp, err := h.doWithDedupe(req, opts)
+		if err != nil {
+			return nil, err
+		}
+		return resp.Body, nil
+	}
+
+	host := req.URL.Host
+	key := cacheKey(req, opts.Body)
+	e, ok := h.cache.Get(key)
+	if ok && time.Now().Before(e.ExpiresAt) {
+		h.metrics.observeCache(host, "hit")
+		h.setProviderStatusCode(e.StatusCode)
+		return e.Body, nil
+	}
+	h.metrics.observeCache(host, "miss")
+
+	resp, err := h.doWithDedupe(req, opts)
+	if err != nil {
+		if ok && time.Now().Before(e.StaleUntil) {
+			logrus.WithField("host", host).Warnf("Using stale cached response for failed call: %v", err)
+			h.metrics.observeCache(host, "stale")
+			h.setProviderStatusCode(e.StatusCode)
+			return e.Body, nil
+		}
+		return nil, err
+	}
+
+	if ttl, ok := cacheTTL(opts.CacheTTL, resp.Header); ok {
+		now := time.Now()
+		h.cache.Set(key, &CacheEntry{
+			Body:       resp.Body,
+			StatusCode: resp.StatusCode,
+			ExpiresAt:  now.Add(ttl),
+			StaleUntil: now.Add(ttl + opts.StaleIfError),
+		})
+	}
+	return resp.Body, nil
+}
+
+// cacheKey returns the key of a request, made from its method, URL
+// including the query, headers and body, so responses to calls made
+// with different credentials are never shared
+func cacheKey(req *http.Request, body string) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(req.URL.String()))
+	h.Write([]byte{0})
+	names := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		h.Write([]byte(k))
+		for _, v := range req.Header[k] {
+			h.Write([]byte{1})
+			h.Write([]byte(v))
+		}
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(body))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheTTL returns how long a response can be cached for, honoring the
+// `Cache-Control` header of the response by not caching if the upstream
+// disallows it and capping the TTL to any `max-age` given
+func cacheTTL(ttl time.Duration, header http.Header) (time.Duration, bool) {
+	for _, d := range strings.Split(header.Get("Cache-Control"), ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "no-store" || d == "no-cache" || d == "private":
+			return 0, false
+		case strings.HasPrefix(d, "max-age="):
+			s, err := strconv.Atoi(strings.TrimPrefix(d, "max-age="))
+			if err != nil {
+				continue
+			}
+			if s <= 0 {
+				return 0, false
+			}
+			if ma := time.Duration(s) * time.Second; ma < ttl {
+				ttl = ma
+			}
+		}
+	}
+	return ttl, true
+}
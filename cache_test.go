@@ -0,0 +1,179 @@
+package bridges
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHelper_HTTPCallRawWithOpts_Cache(t *testing.T) {
+	var calls, down int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if cc := r.URL.Query().Get("cc"); len(cc) > 0 {
+			w.Header().Set("Cache-Control", cc)
+		}
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	}))
+	defer upstream.Close()
+
+	h := NewHelper(nil).WithCache(NewLRUCache(10))
+	call := func(query map[string]interface{}, opts CallOpts) (string, error) {
+		opts.Query = query
+		b, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+		return string(b), err
+	}
+
+	// Not cached without a TTL
+	b, err := call(nil, CallOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"call":1}`, b)
+	b, err = call(nil, CallOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"call":2}`, b)
+
+	opts := CallOpts{CacheTTL: 50 * time.Millisecond, StaleIfError: time.Minute}
+	b, err = call(nil, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"call":3}`, b)
+	b, err = call(nil, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"call":3}`, b)
+
+	// Keyed on the query
+	b, err = call(map[string]interface{}{"fsym": "ETH"}, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"call":4}`, b)
+
+	// Stale response used once expired and the call fails
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&down, 1)
+	b, err = call(nil, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"call":3}`, b)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+
+	// No fallback without a cached response
+	_, err = call(map[string]interface{}{"fsym": "BTC"}, opts)
+	assert.EqualError(t, err, "Unexpected api status code: 503")
+	atomic.StoreInt32(&down, 0)
+
+	// Cache-Control disallowing caching is honored
+	_, err = call(map[string]interface{}{"cc": "no-store"}, opts)
+	assert.Nil(t, err)
+	b, err = call(map[string]interface{}{"cc": "no-store"}, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"call":%d}`, atomic.LoadInt32(&calls)), b)
+}
+
+func TestHelper_HTTPCallRawWithOpts_CacheKeyedOnHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"key":%q}`, r.Header.Get("X-Api-Key"))
+	}))
+	defer upstream.Close()
+
+	h := NewHelper(nil).WithCache(NewLRUCache(10))
+	call := func(key string) string {
+		b, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, CallOpts{
+			CacheTTL: time.Minute,
+			Auth:     &Header{Key: "X-Api-Key", Value: key},
+		})
+		assert.Nil(t, err)
+		return string(b)
+	}
+
+	assert.Equal(t, `{"key":"a"}`, call("a"))
+	assert.Equal(t, `{"key":"b"}`, call("b"))
+	assert.Equal(t, `{"key":"a"}`, call("a"))
+}
+
+func TestHelper_HTTPCallRawWithOpts_CacheProviderStatusCode(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer upstream.Close()
+
+	c := NewLRUCache(10)
+	opts := CallOpts{CacheTTL: time.Minute, ExpectedCode: http.StatusAccepted}
+	_, err := NewHelper(nil).WithCache(c).HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.Nil(t, err)
+
+	// A cache hit gives the status code of the cached response
+	h := NewHelper(nil).WithCache(c)
+	_, err = h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusAccepted, h.ProviderStatusCode())
+}
+
+func TestCacheKey(t *testing.T) {
+	req := func(url, auth string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Authorization", auth)
+		return r
+	}
+	assert.Equal(t, cacheKey(req("http://a?q=1", "x"), ""), cacheKey(req("http://a?q=1", "x"), ""))
+	assert.NotEqual(t, cacheKey(req("http://a?q=1", "x"), ""), cacheKey(req("http://a?q=2", "x"), ""))
+	assert.NotEqual(t, cacheKey(req("http://a?q=1", "x"), ""), cacheKey(req("http://a?q=1", "y"), ""))
+	assert.NotEqual(t, cacheKey(req("http://a?q=1", "x"), "a"), cacheKey(req("http://a?q=1", "x"), "b"))
+}
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		ttl          time.Duration
+		ok           bool
+	}{
+		{"", time.Minute, true},
+		{"public", time.Minute, true},
+		{"max-age=30", 30 * time.Second, true},
+		{"public, max-age=3600", time.Minute, true},
+		{"max-age=0", 0, false},
+		{"no-store", 0, false},
+		{"No-Cache", 0, false},
+		{"private, max-age=30", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.cacheControl, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("Cache-Control", test.cacheControl)
+			ttl, ok := cacheTTL(time.Minute, h)
+			assert.Equal(t, test.ok, ok)
+			if test.ok {
+				assert.Equal(t, test.ttl, ttl)
+			}
+		})
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	e := func(b string) *CacheEntry {
+		return &CacheEntry{Body: []byte(b), ExpiresAt: time.Now().Add(time.Minute), StaleUntil: time.Now().Add(time.Minute)}
+	}
+
+	c.Set("a", e("a"))
+	c.Set("b", e("b"))
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	c.Set("c", e("c"))
+	assert.Equal(t, 2, c.Len())
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", string(v.Body))
+
+	c.Set("expired", &CacheEntry{StaleUntil: time.Now().Add(-time.Second)})
+	_, ok = c.Get("expired")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,422 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is the prefix of the environment variables read by LoadConfig
+const EnvPrefix = "BRIDGES_"
+
+// DefaultAddr is the address listened on when none is configured
+const DefaultAddr = ":8080"
+
+// Config is the configuration of a Server, loaded by LoadConfig from a YAML
+// or JSON file and environment variables, then given to NewServerWithConfig.
+// Each field can be set by the environment variable named in its `env` tag,
+// prefixed by EnvPrefix, such as `BRIDGES_LOG_LEVEL`.
+type Config struct {
+	// Addr is the TCP address the inbuilt http server listens on
+	Addr string `json:"addr" yaml:"addr" env:"ADDR"`
+	// Runtime is the runtime the server is started in by Start,
+	// detected from the environment if empty
+	Runtime string `json:"runtime" yaml:"runtime" env:"RUNTIME"`
+	// TLS serves the inbuilt http server over HTTPS
+	TLS TLSConfig `json:"tls" yaml:"tls" env:"TLS_"`
+	// Timeout is the default maximum duration of a run
+	Timeout Duration `json:"timeout" yaml:"timeout" env:"TIMEOUT"`
+	// ShutdownTimeout is the duration given for in-flight requests on shutdown
+	ShutdownTimeout Duration `json:"shutdownTimeout" yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT"`
+	// DrainDelay is the duration readiness is failed for before shutting down
+	DrainDelay Duration `json:"drainDelay" yaml:"drainDelay" env:"DRAIN_DELAY"`
+	// HealthCheckTimeout is the duration given for bridge health checks
+	HealthCheckTimeout Duration `json:"healthCheckTimeout" yaml:"healthCheckTimeout" env:"HEALTH_CHECK_TIMEOUT"`
+	// MaxBodySize is the maximum size of a request body in bytes, where zero is unlimited
+	MaxBodySize int64 `json:"maxBodySize" yaml:"maxBodySize" env:"MAX_BODY_SIZE"`
+	// ResponseSchema is the format of the responses given to the node
+	ResponseSchema ResponseSchema `json:"responseSchema" yaml:"responseSchema" env:"RESPONSE_SCHEMA"`
+	// PreciseNumbers keeps numbers in upstream responses and results exact
+	PreciseNumbers bool `json:"preciseNumbers" yaml:"preciseNumbers" env:"PRECISE_NUMBERS"`
+	// Log is the logging configuration
+	Log LogConfig `json:"log" yaml:"log" env:"LOG_"`
+	// Auth is the inbound authentication of all the bridges
+	Auth AuthConfig `json:"auth" yaml:"auth" env:"AUTH_"`
+	// Bridges are the overrides of each bridge keyed by its name. Environment
+	// variables are named `BRIDGES_BRIDGE_<NAME>_<FIELD>`, such as
+	// `BRIDGES_BRIDGE_CRYPTOCOMPARE_ACCESS_TOKEN`, with the name matched
+	// case insensitively.
+	Bridges map[string]BridgeConfig `json:"bridges" yaml:"bridges"`
+}
+
+// TLSConfig is the TLS configuration of the inbuilt http server
+type TLSConfig struct {
+	CertFile string `json:"certFile" yaml:"certFile" env:"CERT_FILE"`
+	KeyFile  string `json:"keyFile" yaml:"keyFile" env:"KEY_FILE"`
+	// ClientCAFile is the CA bundle client certificates are verified
+	// against, where clients must give a certificate if it's set
+	ClientCAFile string `json:"clientCAFile" yaml:"clientCAFile" env:"CLIENT_CA_FILE"`
+}
+
+// LogConfig is the logging configuration
+type LogConfig struct {
+	// Level is the logrus level, such as `info` or `debug`
+	Level string `json:"level" yaml:"level" env:"LEVEL"`
+	// Format is either `text` or `json`
+	Format string `json:"format" yaml:"format" env:"FORMAT"`
+}
+
+// AuthConfig is the inbound authentication configuration, where if both
+// are set then requests must give a valid HMAC signature
+type AuthConfig struct {
+	// Token is matched against the node's bearer token
+	Token string `json:"token" yaml:"token" env:"TOKEN"`
+	// HMACSecret is the shared secret the request body is signed with
+	HMACSecret string `json:"hmacSecret" yaml:"hmacSecret" env:"HMAC_SECRET"`
+}
+
+// BridgeConfig overrides the options of a bridge
+type BridgeConfig struct {
+	Timeout        Duration       `json:"timeout" yaml:"timeout" env:"TIMEOUT"`
+	AccessToken    string         `json:"accessToken" yaml:"accessToken" env:"ACCESS_TOKEN"`
+	ResponseSchema ResponseSchema `json:"responseSchema" yaml:"responseSchema" env:"RESPONSE_SCHEMA"`
+	Auth           AuthConfig     `json:"auth" yaml:"auth" env:"AUTH_"`
+}
+
+// Duration is a time.Duration given in config as a string such as `10s`
+type Duration time.Duration
+
+// UnmarshalJSON parses the duration from a string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration: %s", b)
+	}
+	return d.set(s)
+}
+
+// UnmarshalYAML parses the duration from a string
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.set(s)
+}
+
+func (d *Duration) set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %s", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// LoadConfig loads the config from the YAML or JSON file, if given, followed by
+// any environment variables, then validates it
+func LoadConfig(file string) (*Config, error) {
+	c := &Config{}
+	if len(file) > 0 {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json":
+			d := json.NewDecoder(bytes.NewReader(b))
+			d.DisallowUnknownFields()
+			err = d.Decode(c)
+		case ".yaml", ".yml":
+			err = yaml.UnmarshalStrict(b, c)
+		default:
+			return nil, fmt.Errorf("Unsupported config file: %s", file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid config file %s: %v", file, err)
+		}
+	}
+	if err := c.loadEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks the config, giving an error describing every invalid field
+func (c *Config) Validate() error {
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if _, err := NewRuntime(c.Runtime); len(c.Runtime) > 0 && err != nil {
+		add("runtime must be one of %v, got %q", runtimeNames, c.Runtime)
+	}
+	if (len(c.TLS.CertFile) > 0) != (len(c.TLS.KeyFile) > 0) {
+		add("tls.certFile and tls.keyFile must be given together")
+	}
+	if len(c.TLS.ClientCAFile) > 0 && len(c.TLS.CertFile) == 0 {
+		add("tls.clientCAFile requires tls.certFile and tls.keyFile")
+	}
+	for name, f := range map[string]string{
+		"tls.certFile":     c.TLS.CertFile,
+		"tls.keyFile":      c.TLS.KeyFile,
+		"tls.clientCAFile": c.TLS.ClientCAFile,
+	} {
+		if _, err := os.Stat(f); len(f) > 0 && err != nil {
+			add("%s: %v", name, err)
+		}
+	}
+	for name, d := range map[string]Duration{
+		"timeout":            c.Timeout,
+		"shutdownTimeout":    c.ShutdownTimeout,
+		"drainDelay":         c.DrainDelay,
+		"healthCheckTimeout": c.HealthCheckTimeout,
+	} {
+		if d < 0 {
+			add("%s must not be negative", name)
+		}
+	}
+	if c.MaxBodySize < 0 {
+		add("maxBodySize must not be negative")
+	}
+	if len(c.Log.Level) > 0 {
+		if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+			add("log.level: %v", err)
+		}
+	}
+	switch c.Log.Format {
+	case "", "text", "json":
+	default:
+		add("log.format must be one of [text json], got %q", c.Log.Format)
+	}
+	if !validSchema(c.ResponseSchema) {
+		add("responseSchema must be one of [%s %s %s], got %q", SchemaLegacy, SchemaV2, SchemaAuto, c.ResponseSchema)
+	}
+	for name, bc := range c.Bridges {
+		if bc.Timeout < 0 {
+			add("bridges.%s.timeout must not be negative", name)
+		}
+		if !validSchema(bc.ResponseSchema) {
+			add("bridges.%s.responseSchema must be one of [%s %s %s], got %q",
+				name, SchemaLegacy, SchemaV2, SchemaAuto, bc.ResponseSchema)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// NewServerWithConfig returns a new Server with the bridges as NewServer does,
+// configured by the config. Logging is configured globally.
+func NewServerWithConfig(c *Config, bridges ...Bridge) (*Server, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := NewServer(bridges...)
+	names := make(map[string]string)
+	for _, b := range s.pathMap {
+		names[strings.ToLower(b.Opts().Name)] = b.Opts().Name
+	}
+	s.overrides = make(map[string]BridgeConfig)
+	for name, bc := range c.Bridges {
+		n, ok := names[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("Invalid config: bridges.%s doesn't match the name of any bridge", name)
+		}
+		s.overrides[n] = bc
+	}
+	if err := s.checkPaths(); err != nil {
+		return nil, err
+	}
+
+	s.Addr = c.Addr
+	if len(s.Addr) == 0 {
+		s.Addr = DefaultAddr
+	}
+	s.Runtime = c.Runtime
+	if len(c.TLS.CertFile) > 0 {
+		tc := c.TLS
+		s.TLS = &tc
+	}
+	s.Timeout = time.Duration(c.Timeout)
+	s.ShutdownTimeout = time.Duration(c.ShutdownTimeout)
+	s.DrainDelay = time.Duration(c.DrainDelay)
+	s.HealthCheckTimeout = time.Duration(c.HealthCheckTimeout)
+	s.MaxBodySize = c.MaxBodySize
+	s.ResponseSchema = c.ResponseSchema
+	s.PreciseNumbers = c.PreciseNumbers
+	s.InboundAuth = c.Auth.inboundAuth()
+
+	if len(c.Log.Level) > 0 {
+		lvl, _ := logrus.ParseLevel(c.Log.Level)
+		logrus.SetLevel(lvl)
+	}
+	if c.Log.Format == "json" {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	} else if c.Log.Format == "text" {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	}
+	return s, nil
+}
+
+func (a AuthConfig) inboundAuth() InboundAuth {
+	if len(a.HMACSecret) > 0 {
+		return &HMACSignature{Secret: a.HMACSecret}
+	} else if len(a.Token) > 0 {
+		return &BearerToken{Token: a.Token}
+	}
+	return nil
+}
+
+// opts returns the options of the bridge with any overrides from config applied
+func (s *Server) opts(b Bridge) *Opts {
+	opts := b.Opts()
+	bc, ok := s.overrides[opts.Name]
+	if !ok {
+		return opts
+	}
+
+	o := *opts
+	if bc.Timeout > 0 {
+		o.Timeout = time.Duration(bc.Timeout)
+	}
+	if len(bc.AccessToken) > 0 {
+		o.AccessToken = bc.AccessToken
+	}
+	if len(bc.ResponseSchema) > 0 {
+		o.ResponseSchema = bc.ResponseSchema
+	}
+	if a := bc.Auth.inboundAuth(); a != nil {
+		o.InboundAuth = a
+	}
+	return &o
+}
+
+func validSchema(rs ResponseSchema) bool {
+	switch rs {
+	case "", SchemaLegacy, SchemaV2, SchemaAuto:
+		return true
+	}
+	return false
+}
+
+// loadEnv sets the fields of the config from the prefixed environment variables
+func (c *Config) loadEnv(environ []string) error {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 && strings.HasPrefix(kv, EnvPrefix) {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	if err := setEnv(reflect.ValueOf(c).Elem(), EnvPrefix, env); err != nil {
+		return err
+	}
+
+	bp := EnvPrefix + "BRIDGE_"
+	fields := envFields(reflect.TypeOf(BridgeConfig{}), "")
+	for k := range env {
+		if !strings.HasPrefix(k, bp) {
+			continue
+		}
+		for _, f := range fields {
+			if !strings.HasSuffix(k, "_"+f) || len(k) <= len(bp)+len(f)+1 {
+				continue
+			}
+			name := k[len(bp) : len(k)-len(f)-1]
+			key := name
+			for n := range c.Bridges {
+				if strings.EqualFold(n, name) {
+					key = n
+				}
+			}
+			if c.Bridges == nil {
+				c.Bridges = make(map[string]BridgeConfig)
+			}
+			bc := c.Bridges[key]
+			if err := setEnv(reflect.ValueOf(&bc).Elem(), k[:len(k)-len(f)], env); err != nil {
+				return err
+			}
+			c.Bridges[key] = bc
+			break
+		}
+	}
+	return nil
+}
+
+// setEnv sets the fields of the struct from the environment variables
+// named by their `env` tags, recursing into nested structs
+func setEnv(v reflect.Value, prefix string, env map[string]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct {
+			if err := setEnv(f, prefix+tag, env); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := env[prefix+tag]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch {
+		case f.Type() == reflect.TypeOf(Duration(0)):
+			err = f.Addr().Interface().(*Duration).set(s)
+		case f.Kind() == reflect.String:
+			f.SetString(s)
+		case f.Kind() == reflect.Bool:
+			var b bool
+			if b, err = strconv.ParseBool(s); err == nil {
+				f.SetBool(b)
+			}
+		case f.Kind() == reflect.Int64:
+			var n int64
+			if n, err = strconv.ParseInt(s, 10, 64); err == nil {
+				f.SetInt(n)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("Invalid config: %s%s: %v", prefix, tag, err)
+		}
+	}
+	return nil
+}
+
+// envFields returns the env variable names of all the fields of the struct
+func envFields(t reflect.Type, prefix string) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			fields = append(fields, envFields(t.Field(i).Type, prefix+tag)...)
+		} else {
+			fields = append(fields, prefix+tag)
+		}
+	}
+	return fields
+}
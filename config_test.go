@@ -0,0 +1,140 @@
+package bridges
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	f := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(f, []byte(content), 0600))
+	return f
+}
+
+func TestLoadConfig(t *testing.T) {
+	f := writeConfig(t, "bridges.yaml", `
+addr: ":9090"
+timeout: 30s
+maxBodySize: 1024
+log:
+  level: debug
+  format: json
+bridges:
+  CryptoCompare:
+    timeout: 5s
+`)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	env := map[string]string{
+		"BRIDGES_TIMEOUT":                            "10s",
+		"BRIDGES_AUTH_TOKEN":                         "token",
+		"BRIDGES_BRIDGE_CRYPTOCOMPARE_ACCESS_TOKEN":  "access",
+		"BRIDGES_BRIDGE_GASSTATION_RESPONSE_SCHEMA":  "v2",
+		"BRIDGES_BRIDGE_GASSTATION_AUTH_HMAC_SECRET": "secret",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	c, err := LoadConfig(f)
+	assert.Nil(t, err)
+	assert.Equal(t, ":9090", c.Addr)
+	assert.Equal(t, Duration(10*time.Second), c.Timeout)
+	assert.Equal(t, int64(1024), c.MaxBodySize)
+	assert.Equal(t, LogConfig{Level: "debug", Format: "json"}, c.Log)
+	assert.Equal(t, "token", c.Auth.Token)
+	assert.Equal(t, BridgeConfig{Timeout: Duration(5 * time.Second), AccessToken: "access"}, c.Bridges["CryptoCompare"])
+	assert.Equal(t, BridgeConfig{ResponseSchema: SchemaV2, Auth: AuthConfig{HMACSecret: "secret"}}, c.Bridges["GASSTATION"])
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	f := writeConfig(t, "bridges.json", `{
+		"runtime": "azure",
+		"tls": {"certFile": "cert.pem"},
+		"timeout": "-1s",
+		"log": {"level": "loud", "format": "xml"},
+		"bridges": {"CryptoCompare": {"responseSchema": "v3"}}
+	}`)
+	defer os.RemoveAll(filepath.Dir(f))
+
+	_, err := LoadConfig(f)
+	assert.NotNil(t, err)
+	for _, e := range []string{
+		`runtime must be one of [http lambda gcp cloudevents], got "azure"`,
+		"tls.certFile and tls.keyFile must be given together",
+		"tls.certFile: stat cert.pem: no such file or directory",
+		"timeout must not be negative",
+		`log.level: not a valid logrus Level: "loud"`,
+		`log.format must be one of [text json], got "xml"`,
+		`bridges.CryptoCompare.responseSchema must be one of [legacy v2 auto], got "v3"`,
+	} {
+		assert.Contains(t, err.Error(), e)
+	}
+
+	err = (&Config{TLS: TLSConfig{ClientCAFile: "ca.pem"}}).Validate()
+	assert.EqualError(t, err, "Invalid config: tls.clientCAFile requires tls.certFile and tls.keyFile; "+
+		"tls.clientCAFile: stat ca.pem: no such file or directory")
+
+	f = writeConfig(t, "bridges.yml", "port: 8080")
+	defer os.RemoveAll(filepath.Dir(f))
+	_, err = LoadConfig(f)
+	assert.True(t, strings.HasPrefix(err.Error(), "Invalid config file"))
+
+	os.Setenv("BRIDGES_MAX_BODY_SIZE", "large")
+	defer os.Unsetenv("BRIDGES_MAX_BODY_SIZE")
+	_, err = LoadConfig("")
+	assert.EqualError(t, err, `Invalid config: BRIDGES_MAX_BODY_SIZE: strconv.ParseInt: parsing "large": invalid syntax`)
+}
+
+func TestNewServerWithConfig(t *testing.T) {
+	defer logrus.SetFormatter(&logrus.TextFormatter{})
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	s, err := NewServerWithConfig(&Config{
+		Timeout:     Duration(time.Second),
+		MaxBodySize: 64,
+		Log:         LogConfig{Level: "warn"},
+		Bridges: map[string]BridgeConfig{
+			"requireparam": {Timeout: Duration(time.Minute), Auth: AuthConfig{Token: "token"}},
+		},
+	}, &RequireParam{})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultAddr, s.Addr)
+	assert.Equal(t, time.Second, s.Timeout)
+	assert.Equal(t, time.Minute, s.timeout(&RequireParam{}))
+	assert.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+	mux := s.Mux()
+
+	body := `{"id":"1234","data":{"symbol":"ETH"}}`
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer token")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body+strings.Repeat(" ", 64)))
+	req.Header.Set("Authorization", "Bearer token")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	_, err = NewServerWithConfig(&Config{Bridges: map[string]BridgeConfig{"Unknown": {}}}, &RequireParam{})
+	assert.EqualError(t, err, "Invalid config: bridges.Unknown doesn't match the name of any bridge")
+}
@@ -0,0 +1,338 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Spec is the specification of a DeclarativeBridge, which calls an API and
+// extracts a value from its response without writing Go. The URL, query, headers
+// and body are templates given the request `data`, such as `{{.base}}`, with
+// `{{env "KEY"}}` giving an environment variable, and `{{urlquery .base}}` or
+// `{{json .base}}` escaping a value. Values in the URL are path escaped unless
+// escaped otherwise or from the environment.
+type Spec struct {
+	Name             string            `json:"name" yaml:"name"`
+	Path             string            `json:"path" yaml:"path"`
+	Lambda           bool              `json:"lambda" yaml:"lambda"`
+	Method           string            `json:"method" yaml:"method"`
+	URL              string            `json:"url" yaml:"url"`
+	Query            map[string]string `json:"query" yaml:"query"`
+	Headers          map[string]string `json:"headers" yaml:"headers"`
+	Body             string            `json:"body" yaml:"body"`
+	Auth             *AuthSpec         `json:"auth" yaml:"auth"`
+	ExpectedCode     int               `json:"expectedCode" yaml:"expectedCode"`
+	Timeout          string            `json:"timeout" yaml:"timeout"`
+	Extract          string            `json:"extract" yaml:"extract"`
+	Transforms       []TransformSpec   `json:"transforms" yaml:"transforms"`
+	DataTransformers bool              `json:"dataTransformers" yaml:"dataTransformers"`
+}
+
+// AuthSpec is the API authentication of a Spec, being of type `param` or
+// `header`. The secret is read from the environment variable named in Env,
+// or given as Value.
+type AuthSpec struct {
+	Type  string `json:"type" yaml:"type"`
+	Key   string `json:"key" yaml:"key"`
+	Env   string `json:"env" yaml:"env"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// TransformSpec is a transformer of a Spec, being of type `jsonparse` with
+// a Path, `multiply` with Times, or `encode` with an Encode type
+type TransformSpec struct {
+	Type   string `json:"type" yaml:"type"`
+	Path   string `json:"path" yaml:"path"`
+	Times  string `json:"times" yaml:"times"`
+	Encode string `json:"encode" yaml:"encode"`
+}
+
+// DeclarativeBridge is the Bridge implementation built from a Spec
+type DeclarativeBridge struct {
+	spec    Spec
+	opts    *Opts
+	auth    Auth
+	url     *template.Template
+	body    *template.Template
+	query   map[string]*template.Template
+	headers map[string]*template.Template
+}
+
+// NewDeclarativeBridge returns a DeclarativeBridge for the spec, erroring if
+// the spec is invalid or a secret it references isn't set
+func NewDeclarativeBridge(spec Spec) (*DeclarativeBridge, error) {
+	if len(spec.URL) == 0 {
+		return nil, fmt.Errorf("Invalid spec %q: url is required", spec.Name)
+	}
+	if len(spec.Method) == 0 {
+		spec.Method = http.MethodGet
+	}
+	d := &DeclarativeBridge{
+		spec:    spec,
+		opts:    &Opts{Name: spec.Name, Path: spec.Path, Lambda: spec.Lambda, DataTransformers: spec.DataTransformers},
+		query:   make(map[string]*template.Template),
+		headers: make(map[string]*template.Template),
+	}
+
+	var err error
+	if d.url, err = parseTemplate("url", spec.URL); err != nil {
+		return nil, fmt.Errorf("Invalid spec %q: %v", spec.Name, err)
+	}
+	escapeActions(d.url.Tree, d.url.Tree.Root)
+	if d.body, err = parseTemplate("body", spec.Body); err != nil {
+		return nil, fmt.Errorf("Invalid spec %q: %v", spec.Name, err)
+	}
+	for k, v := range spec.Query {
+		if d.query[k], err = parseTemplate("query "+k, v); err != nil {
+			return nil, fmt.Errorf("Invalid spec %q: %v", spec.Name, err)
+		}
+	}
+	for k, v := range spec.Headers {
+		if d.headers[k], err = parseTemplate("header "+k, v); err != nil {
+			return nil, fmt.Errorf("Invalid spec %q: %v", spec.Name, err)
+		}
+	}
+	if len(spec.Timeout) > 0 {
+		if d.opts.Timeout, err = time.ParseDuration(spec.Timeout); err != nil {
+			return nil, fmt.Errorf("Invalid spec %q: invalid timeout: %s", spec.Name, spec.Timeout)
+		}
+	}
+	if d.auth, err = spec.Auth.auth(); err != nil {
+		return nil, fmt.Errorf("Invalid spec %q: %v", spec.Name, err)
+	}
+	if d.opts.Transformers, err = spec.transformers(); err != nil {
+		return nil, fmt.Errorf("Invalid spec %q: %v", spec.Name, err)
+	}
+	return d, nil
+}
+
+// LoadSpec returns a DeclarativeBridge for the YAML or JSON spec file. If the
+// spec has no name or path, then the file name is used.
+func LoadSpec(file string) (*DeclarativeBridge, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		err = d.Decode(&spec)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, &spec)
+	default:
+		return nil, fmt.Errorf("Unsupported spec file: %s", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid spec file %s: %v", file, err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if len(spec.Name) == 0 {
+		spec.Name = base
+	}
+	if len(spec.Path) == 0 {
+		spec.Path = "/" + base
+	}
+	return NewDeclarativeBridge(spec)
+}
+
+// LoadSpecs returns a DeclarativeBridge for each YAML and JSON spec file in
+// the directory, so they can be given to NewServer alongside other bridges
+func LoadSpecs(dir string) ([]Bridge, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var bs []Bridge
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+		if f.IsDir() {
+			continue
+		}
+		b, err := LoadSpec(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		bs = append(bs, b)
+	}
+	return bs, nil
+}
+
+// Opts is the bridge.Bridge implementation
+func (d *DeclarativeBridge) Opts() *Opts {
+	return d.opts
+}
+
+// Run is the bridge.Bridge implementation, calling the API of the spec and
+// returning its response to be ran through the transformers
+func (d *DeclarativeBridge) Run(h *Helper) (interface{}, error) {
+	// Numbers are kept as given, rather than formatted as float64
+	data := make(map[string]interface{})
+	if h.Data != nil && h.Data.IsObject() {
+		dec := json.NewDecoder(strings.NewReader(h.Data.Raw))
+		dec.UseNumber()
+		if err := dec.Decode(&data); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := execTemplate(d.url, data)
+	if err != nil {
+		return nil, err
+	}
+	opts := CallOpts{
+		Auth:         d.auth,
+		Query:        make(map[string]interface{}),
+		Headers:      make(map[string]string),
+		ExpectedCode: d.spec.ExpectedCode,
+	}
+	if opts.Body, err = execTemplate(d.body, data); err != nil {
+		return nil, err
+	}
+	for k, t := range d.query {
+		if opts.Query[k], err = execTemplate(t, data); err != nil {
+			return nil, err
+		}
+	}
+	for k, t := range d.headers {
+		if opts.Headers[k], err = execTemplate(t, data); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := h.HTTPCallRawWithOpts(d.spec.Method, u, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		return nil, errors.New("Invalid JSON response from api")
+	}
+	return json.RawMessage(b), nil
+}
+
+func (a *AuthSpec) auth() (Auth, error) {
+	if a == nil {
+		return nil, nil
+	}
+	value := a.Value
+	if len(a.Env) > 0 {
+		if value = os.Getenv(a.Env); len(value) == 0 {
+			return nil, fmt.Errorf("auth env %s is not set", a.Env)
+		}
+	}
+	switch a.Type {
+	case AuthParam, AuthHeader:
+		return NewAuth(a.Type, a.Key, value), nil
+	}
+	return nil, fmt.Errorf("unsupported auth type: %s", a.Type)
+}
+
+func (s *Spec) transformers() ([]Transformer, error) {
+	var ts []Transformer
+	if len(s.Extract) > 0 {
+		ts = append(ts, &JSONParse{Path: s.Extract})
+	}
+	for _, t := range s.Transforms {
+		switch t.Type {
+		case "jsonparse":
+			ts = append(ts, &JSONParse{Path: t.Path})
+		case "multiply":
+			ts = append(ts, &Multiply{Times: t.Times})
+		case "encode":
+			ts = append(ts, &Encode{Type: t.Encode})
+		default:
+			return nil, fmt.Errorf("unsupported transform type: %s", t.Type)
+		}
+	}
+	return ts, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"env":        os.Getenv,
+	"json":       jsonEscape,
+	"pathescape": pathEscape,
+}
+
+// unescapedFuncs are the template funcs whose output isn't path escaped
+// in URLs, being escaped already or from the environment
+var unescapedFuncs = map[string]bool{
+	"env":        true,
+	"urlquery":   true,
+	"json":       true,
+	"pathescape": true,
+}
+
+func jsonEscape(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func pathEscape(args ...interface{}) string {
+	return url.PathEscape(fmt.Sprint(args...))
+}
+
+// escapeActions pipes the output of each action in the template to
+// pathescape, unless the action already ends with one of unescapedFuncs
+func escapeActions(tree *parse.Tree, n parse.Node) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			escapeActions(tree, c)
+		}
+	case *parse.IfNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.WithNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		cmds := n.Pipe.Cmds
+		if id, ok := cmds[len(cmds)-1].Args[0].(*parse.IdentifierNode); ok && unescapedFuncs[id.Ident] {
+			return
+		}
+		id := parse.NewIdentifier("pathescape").SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{id}})
+	}
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// execTemplate executes the template with the request data, giving
+// a ValidationError if the data is missing a parameter
+func execTemplate(t *template.Template, data map[string]interface{}) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", &ValidationError{Violations: []Violation{{Rule: "template", Message: err.Error()}}}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,153 @@
+package bridges
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newSpecUpstream(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"RAW":{"%s":{"%s":{"PRICE":1.5}}},"path":"%s"}`,
+			r.URL.Query().Get("fsym"), r.URL.Query().Get("tsyms"), r.URL.Path)
+	}))
+}
+
+func writeSpecs(t *testing.T, upstream string) string {
+	dir, err := ioutil.TempDir("", "specs")
+	assert.Nil(t, err)
+
+	yml := `
+name: CryptoCompare
+url: ` + upstream + `/data/price
+query:
+  fsym: "{{.from}}"
+  tsyms: "{{.to}}"
+auth:
+  type: header
+  key: X-API-Key
+  env: SPEC_TEST_API_KEY
+timeout: 5s
+transforms:
+  - type: jsonparse
+    path: RAW.ETH.USD.PRICE
+  - type: multiply
+    times: 1e18
+  - type: encode
+    encode: uint256
+`
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "cryptocompare.yaml"), []byte(yml), 0600))
+
+	js := fmt.Sprintf(`{
+		"path": "/raw",
+		"url": "%s/data/raw",
+		"query": {"fsym": "{{.from}}", "tsyms": "USD"},
+		"auth": {"type": "header", "key": "X-API-Key", "value": "secret"},
+		"dataTransformers": true
+	}`, upstream)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "raw.json"), []byte(js), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("specs"), 0600))
+	return dir
+}
+
+func TestLoadSpecs(t *testing.T) {
+	upstream := newSpecUpstream(t)
+	defer upstream.Close()
+	dir := writeSpecs(t, upstream.URL)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("SPEC_TEST_API_KEY", "secret")
+	defer os.Unsetenv("SPEC_TEST_API_KEY")
+
+	bs, err := LoadSpecs(dir)
+	assert.Nil(t, err)
+	assert.Len(t, bs, 2)
+	assert.Equal(t, "CryptoCompare", bs[0].Opts().Name)
+	assert.Equal(t, "/cryptocompare", bs[0].Opts().Path)
+	assert.Equal(t, "raw", bs[1].Opts().Name)
+	assert.Equal(t, "/raw", bs[1].Opts().Path)
+
+	mux := NewServer(append(bs, &HelloWorld{})...).Mux()
+
+	code, json := postRunPath(t, mux, "/cryptocompare", map[string]interface{}{
+		"id":   "1234",
+		"data": map[string]interface{}{"from": "ETH", "to": "USD"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "0x00000000000000000000000000000000000000000000000014d1120d7b160000", json.Get("data.result").String())
+	assert.Equal(t, "1500000000000000000", json.Get("data.value").String())
+
+	code, json = postRunPath(t, mux, "/raw", map[string]interface{}{
+		"id":   "1234",
+		"data": map[string]interface{}{"from": "BTC", "path": "RAW.BTC.USD.PRICE"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1.5, json.Get("data").Float())
+
+	code, json = postRunPath(t, mux, "/cryptocompare", map[string]interface{}{
+		"id":   "1234",
+		"data": map[string]interface{}{"from": "ETH"},
+	})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, json.Get("error").String(), `map has no entry for key "to"`)
+}
+
+func TestDeclarativeBridge_Run_Escaping(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"path":%q,"amount":%q,"body":%s}`, r.URL.EscapedPath(), r.URL.Query().Get("amount"), body)
+	}))
+	defer upstream.Close()
+
+	os.Setenv("SPEC_TEST_UPSTREAM", upstream.URL)
+	defer os.Unsetenv("SPEC_TEST_UPSTREAM")
+
+	d, err := NewDeclarativeBridge(Spec{
+		Path:   "/escaping",
+		Method: http.MethodPost,
+		URL:    `{{env "SPEC_TEST_UPSTREAM"}}/price/{{.pair}}/{{urlquery .pair}}{{if .amount}}/{{.amount}}{{end}}`,
+		Query:  map[string]string{"amount": "{{.amount}}"},
+		Body:   `{"amount":{{json .amount}},"note":{{json .note}}}`,
+	})
+	assert.Nil(t, err)
+
+	data := map[string]interface{}{
+		"pair":   "ETH/USD?",
+		"amount": json.Number("1e21"),
+		"note":   `say "hi"`,
+	}
+	code, json := postRunPath(t, NewServer(d).Mux(), "/escaping", map[string]interface{}{"id": "1234", "data": data})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "/price/ETH%2FUSD%3F/ETH%2FUSD%3F/1e21", json.Get("data.path").String())
+	assert.Equal(t, "1e21", json.Get("data.amount").String())
+	assert.Equal(t, "1e21", json.Get("data.body.amount").Raw)
+	assert.Equal(t, `say "hi"`, json.Get("data.body.note").String())
+}
+
+func TestNewDeclarativeBridge_Invalid(t *testing.T) {
+	tests := []struct {
+		spec Spec
+		err  string
+	}{
+		{Spec{Name: "a"}, `Invalid spec "a": url is required`},
+		{Spec{Name: "a", URL: "{{.from"}, `Invalid spec "a": template: url:1: unclosed action`},
+		{Spec{Name: "a", URL: "http://a", Timeout: "soon"}, `Invalid spec "a": invalid timeout: soon`},
+		{Spec{Name: "a", URL: "http://a", Auth: &AuthSpec{Type: "basic"}}, `Invalid spec "a": unsupported auth type: basic`},
+		{Spec{Name: "a", URL: "http://a", Auth: &AuthSpec{Type: "param", Env: "SPEC_TEST_UNSET"}}, `Invalid spec "a": auth env SPEC_TEST_UNSET is not set`},
+		{Spec{Name: "a", URL: "http://a", Transforms: []TransformSpec{{Type: "round"}}}, `Invalid spec "a": unsupported transform type: round`},
+	}
+	for _, test := range tests {
+		_, err := NewDeclarativeBridge(test.spec)
+		assert.EqualError(t, err, test.err)
+	}
+}
@@ -0,0 +1,53 @@
+package bridges
+
+import (
+	"errors"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+)
+
+// defaultGroup is used to deduplicate calls by any helper not created by a server
+var defaultGroup = &singleflight.Group{}
+
+// doWithDedupe performs the http call, collapsing it with any identical call
+// already in-flight so only a single upstream request is made, with the response
+// shared between all the callers.
+//
+// The in-flight call is made with the context of the first caller, so if it's
+// cancelled then the other callers are given the error.
+func (h *Helper) doWithDedupe(req *http.Request, opts CallOpts) (*upstreamResponse, error) {
+	if !opts.Deduplicate {
+		return h.doWithRetry(req, opts)
+	}
+	g := h.group
+	if g == nil {
+		g = defaultGroup
+	}
+
+	var executed bool
+	// The cache key includes the headers, so calls with different
+	// credentials are never collapsed
+	v, err, shared := g.Do(cacheKey(req, opts.Body), func() (interface{}, error) {
+		executed = true
+		return h.doWithRetry(req, opts)
+	})
+	if !executed {
+		h.metrics.observeDedupe(req.URL.Host)
+		var sce *statusCodeError
+		if errors.As(err, &sce) {
+			h.setProviderStatusCode(sce.Code)
+		} else if err == nil {
+			h.setProviderStatusCode(v.(*upstreamResponse).StatusCode)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp := v.(*upstreamResponse)
+	if shared {
+		b := make([]byte, len(resp.Body))
+		copy(b, resp.Body)
+		resp = &upstreamResponse{Body: b, Header: resp.Header, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
@@ -0,0 +1,64 @@
+package bridges
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHelper_HTTPCallRawWithOpts_Deduplicate(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL)
+	assert.Nil(t, err)
+
+	s := NewServer()
+	callers := []Auth{
+		NewAuth(AuthHeader, "API-KEY", "a"),
+		NewAuth(AuthHeader, "API-KEY", "a"),
+		NewAuth(AuthHeader, "API-KEY", "a"),
+		NewAuth(AuthHeader, "API-KEY", "b"),
+	}
+
+	var wg sync.WaitGroup
+	bodies := make([]string, len(callers))
+	codes := make([]int, len(callers))
+	for i, a := range callers {
+		wg.Add(1)
+		go func(i int, a Auth) {
+			defer wg.Done()
+			h := s.newHelper(context.Background(), &HelloWorld{}, nil)
+			b, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, CallOpts{Auth: a, Deduplicate: true})
+			assert.Nil(t, err)
+			bodies[i], codes[i] = string(b), h.ProviderStatusCode()
+		}(i, a)
+	}
+
+	// Give the calls time to be in-flight before responding
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	for _, code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Equal(t, bodies[0], bodies[2])
+	assert.NotEqual(t, bodies[0], bodies[3])
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.metrics.deduplicated.WithLabelValues(u.Host)))
+}
@@ -0,0 +1,234 @@
+package bridges
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PathAttribute is the attribute of a Pub/Sub message naming the
+// path of the bridge to run
+const PathAttribute = "path"
+
+const (
+	cloudEventsContentType = "application/cloudevents+json"
+	pubSubEventType        = "google.cloud.pubsub.topic.v1.messagePublished"
+)
+
+// PubSubMessage is the message given to Pub/Sub-triggered GCP Functions,
+// with the request from the node as the data
+type PubSubMessage struct {
+	Data       []byte            `json:"data"`
+	Attributes map[string]string `json:"attributes"`
+	MessageID  string            `json:"messageId"`
+}
+
+// path returns the path of the bridge to run, defaulting to the given path
+func (m *PubSubMessage) path(def string) string {
+	if p := m.Attributes[PathAttribute]; len(p) > 0 {
+		return p
+	}
+	return def
+}
+
+// cloudEvent is a CloudEvents 1.0 event, in either the binary or
+// structured content mode
+type cloudEvent struct {
+	SpecVersion string          `json:"specversion"`
+	ID          string          `json:"id"`
+	Source      string          `json:"source"`
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data"`
+	DataBase64  string          `json:"data_base64"`
+}
+
+func isCloudEvent(r *http.Request) bool {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == cloudEventsContentType || len(r.Header.Get("Ce-Specversion")) > 0
+}
+
+func parseCloudEvent(r *http.Request, body []byte) (*cloudEvent, error) {
+	var e cloudEvent
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == cloudEventsContentType {
+		if err := json.Unmarshal(body, &e); err != nil {
+			return nil, err
+		}
+		if len(e.DataBase64) > 0 {
+			data, err := base64.StdEncoding.DecodeString(e.DataBase64)
+			if err != nil {
+				return nil, err
+			}
+			e.Data = data
+		}
+	} else {
+		e.SpecVersion = r.Header.Get("Ce-Specversion")
+		e.ID = r.Header.Get("Ce-Id")
+		e.Source = r.Header.Get("Ce-Source")
+		e.Type = r.Header.Get("Ce-Type")
+		e.Data = body
+	}
+
+	if !strings.HasPrefix(e.SpecVersion, "1.") {
+		return nil, fmt.Errorf("Unsupported CloudEvents spec version %q", e.SpecVersion)
+	} else if len(e.ID) == 0 || len(e.Source) == 0 || len(e.Type) == 0 {
+		return nil, errors.New("Invalid CloudEvent: id, source and type are required")
+	}
+	return &e, nil
+}
+
+// request returns the path of the bridge to run and the request from the
+// node in the event data, unwrapping the message of Pub/Sub events
+func (e *cloudEvent) request(path string) (string, []byte, error) {
+	if e.Type != pubSubEventType {
+		return path, e.Data, nil
+	}
+	var d struct {
+		Message PubSubMessage `json:"message"`
+	}
+	if err := json.Unmarshal(e.Data, &d); err != nil {
+		return "", nil, err
+	}
+	return d.Message.path(path), d.Message.Data, nil
+}
+
+// eventHandler returns the handler giving CloudEvents to CloudEventsHandler
+// and any other requests to the Mux
+func (s *Server) eventHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isCloudEvent(r) {
+			s.CloudEventsHandler(w, r)
+		} else {
+			s.ServeHTTP(w, r)
+		}
+	})
+}
+
+// CloudEventsHandler handles CloudEvents delivered over http in either the
+// binary or structured content mode. The event data is the request from the
+// node, ran by the bridge at the request path. For Pub/Sub events, it's the
+// data of the message instead, ran by the bridge at the path in the message's
+// PathAttribute if given. Events are authenticated with the InboundAuth of the
+// bridge ran, falling back to the server's InboundAuth.
+//
+// As event sources don't read the response, the result is sent to the node's
+// responseURL if given, responding with 200 once it's been sent.
+func (s *Server) CloudEventsHandler(w http.ResponseWriter, r *http.Request) {
+	var rt Result
+	var code, status int
+	start := time.Now()
+	schema := s.responseSchema(nil)
+
+	defer func() {
+		if p := recover(); p != nil {
+			code, status = http.StatusInternalServerError, http.StatusInternalServerError
+			rt.SetErrored(s.recovered(p, ""))
+		}
+		if status == 0 {
+			status = code
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(s.response(resolveSchema(schema, &rt), &rt, code)); err != nil {
+			logrus.Errorf("Failed to encode response: %v", err)
+		}
+		s.logRequest(r, status, start)
+	}()
+
+	body, err := s.readBody(r)
+	if err != nil {
+		code = http.StatusInternalServerError
+		if err == errBodyTooLarge {
+			code = http.StatusRequestEntityTooLarge
+		}
+		rt.SetErrored(err)
+		return
+	}
+
+	// The bridge is resolved before authenticating, as Pub/Sub
+	// events name it in the message rather than the request path
+	e, err := parseCloudEvent(r, body)
+	if err != nil {
+		code = http.StatusBadRequest
+		rt.SetErrored(err)
+		return
+	}
+	path, data, err := e.request(s.path(r))
+	if err != nil {
+		code = http.StatusBadRequest
+		rt.SetErrored(err)
+		return
+	}
+	b := s.pathMap[path]
+	schema = s.responseSchema(b)
+	if err = s.authenticateBridge(b, r, body); err != nil {
+		code = http.StatusUnauthorized
+		rt.SetErrored(err)
+		return
+	} else if b == nil {
+		code = http.StatusBadRequest
+		rt.SetErrored(errors.New("Invalid path"))
+		return
+	} else if err = json.Unmarshal(data, &rt); err != nil {
+		code = http.StatusBadRequest
+		rt.SetErrored(err)
+		return
+	}
+
+	schema = resolveSchema(schema, &rt)
+	code, err = s.runEvent(r.Context(), b, &rt)
+	if err != nil {
+		logrus.WithField("jobRunId", rt.JobRunID).Errorf("Failed to send event result: %v", err)
+		status = http.StatusBadGateway
+	} else if len(rt.ResponseURL) > 0 {
+		status = http.StatusOK
+	}
+}
+
+// PubSub is the handler for Pub/Sub-triggered GCP Functions, running the
+// bridge at the path in the message's PathAttribute, defaulting to "/",
+// with the message data. The result is sent to the node's responseURL.
+//
+// An error is returned if the message is invalid or the result couldn't
+// be sent, so the message is retried if enabled for the function.
+func (s *Server) PubSub(ctx context.Context, m PubSubMessage) error {
+	path := m.path("/")
+	b, ok := s.pathMap[path]
+	if !ok {
+		return fmt.Errorf("Invalid path %q", path)
+	}
+	var rt Result
+	if err := json.Unmarshal(m.Data, &rt); err != nil {
+		return err
+	}
+	if len(rt.ResponseURL) == 0 {
+		logrus.WithField("jobRunId", rt.JobRunID).Warn("No responseURL given to send the result to")
+	}
+	_, err := s.runEvent(ctx, b, &rt)
+	return err
+}
+
+// runEvent runs the bridge with the request from an event, sending the
+// result to the node if a responseURL is given
+func (s *Server) runEvent(ctx context.Context, b Bridge, rt *Result) (int, error) {
+	opts := b.Opts()
+	done := s.metrics.startRequest(opts.Name, opts.Path)
+	defer func() {
+		done(rt.Status)
+	}()
+
+	rt.SetJobRunID()
+	code := s.runIdempotent(b, rt, func(rt *Result) int {
+		return s.run(ctx, b, rt)
+	})
+	if len(rt.ResponseURL) == 0 {
+		return code, nil
+	}
+	return code, s.callback(b, rt, code)
+}
@@ -0,0 +1,211 @@
+package bridges
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newNode(t *testing.T) (*httptest.Server, chan *JSON) {
+	results := make(chan *JSON, 1)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		j, err := Parse(body)
+		assert.Nil(t, err)
+		results <- j
+	}))
+	return node, results
+}
+
+func serveEvent(t *testing.T, s *Server, path string, header http.Header, body []byte) (int, *JSON) {
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	assert.Nil(t, err)
+	req.Header = header
+	rr := httptest.NewRecorder()
+	s.eventHandler().ServeHTTP(rr, req)
+	j, err := Parse(rr.Body.Bytes())
+	assert.Nil(t, err)
+	return rr.Code, j
+}
+
+func TestServer_CloudEventsHandler_Binary(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+	h := http.Header{
+		"Content-Type":   {"application/json"},
+		"Ce-Specversion": {"1.0"},
+		"Ce-Id":          {"1"},
+		"Ce-Source":      {"//chainlink/node"},
+		"Ce-Type":        {"com.chainlink.run"},
+	}
+
+	code, resp := serveEvent(t, s, "/path", h, []byte(`{"id": "1234"}`))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1234", resp.Get("jobRunId").String())
+	assert.Equal(t, "hello world", resp.Get("data.key").String())
+
+	code, resp = serveEvent(t, s, "/", h, []byte(`{"id": "1234"}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `Invalid parameter "symbol": missing required parameter`, resp.Get("error").String())
+
+	h.Del("Ce-Type")
+	code, resp = serveEvent(t, s, "/path", h, []byte(`{"id": "1234"}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "Invalid CloudEvent: id, source and type are required", resp.Get("error").String())
+
+	// Requests that aren't CloudEvents are served as usual
+	code, resp = serveEvent(t, s, "/path", http.Header{}, []byte(`{"id": "1234"}`))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello world", resp.Get("data.key").String())
+}
+
+func TestServer_CloudEventsHandler_SchemaAuto(t *testing.T) {
+	s := NewServer(&LambdaPath{})
+	s.ResponseSchema = SchemaAuto
+	h := http.Header{
+		"Content-Type":   {"application/json"},
+		"Ce-Specversion": {"1.0"},
+		"Ce-Id":          {"1"},
+		"Ce-Source":      {"//chainlink/node"},
+		"Ce-Type":        {"com.chainlink.run"},
+	}
+
+	code, resp := serveEvent(t, s, "/path", h, []byte(`{"id": "1234", "data": {}}`))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1234", resp.Get("jobRunID").String())
+	assert.Equal(t, int64(http.StatusOK), resp.Get("statusCode").Int())
+	assert.False(t, resp.Get("jobRunId").Exists())
+
+	code, resp = serveEvent(t, s, "/path", h, []byte(`{"jobRunId": "1234"}`))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1234", resp.Get("jobRunId").String())
+	assert.False(t, resp.Get("jobRunID").Exists())
+}
+
+func TestServer_CloudEventsHandler_Structured(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+	h := http.Header{"Content-Type": {"application/cloudevents+json; charset=utf-8"}}
+
+	code, resp := serveEvent(t, s, "/", h, []byte(`{
+		"specversion": "1.0",
+		"id": "1",
+		"source": "//chainlink/node",
+		"type": "com.chainlink.run",
+		"datacontenttype": "application/json",
+		"data": {"id": "1234", "data": {"symbol": "ETH"}}
+	}`))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ETH", resp.Get("data.symbol").String())
+
+	code, resp = serveEvent(t, s, "/", h, []byte(`{"specversion": "0.3", "id": "1", "source": "s", "type": "t"}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `Unsupported CloudEvents spec version "0.3"`, resp.Get("error").String())
+}
+
+func TestServer_CloudEventsHandler_PubSub(t *testing.T) {
+	node, results := newNode(t)
+	defer node.Close()
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+
+	data, err := json.Marshal(map[string]interface{}{"id": "1234", "responseURL": node.URL})
+	assert.Nil(t, err)
+	event, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"data":       base64.StdEncoding.EncodeToString(data),
+			"attributes": map[string]string{PathAttribute: "/path"},
+			"messageId":  "1",
+		},
+		"subscription": "projects/bridges/subscriptions/runs",
+	})
+	assert.Nil(t, err)
+	h := http.Header{
+		"Content-Type":   {"application/json"},
+		"Ce-Specversion": {"1.0"},
+		"Ce-Id":          {"1"},
+		"Ce-Source":      {"//pubsub.googleapis.com/projects/bridges/topics/runs"},
+		"Ce-Type":        {"google.cloud.pubsub.topic.v1.messagePublished"},
+	}
+
+	code, resp := serveEvent(t, s, "/", h, event)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello world", resp.Get("data.key").String())
+
+	result := <-results
+	assert.Equal(t, "1234", result.Get("jobRunId").String())
+	assert.Equal(t, "hello world", result.Get("data.key").String())
+}
+
+func TestServer_CloudEventsHandler_PubSubAuth(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &AuthHelloWorld{})
+
+	data, err := json.Marshal(map[string]interface{}{"id": "1234"})
+	assert.Nil(t, err)
+	event, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"data":       base64.StdEncoding.EncodeToString(data),
+			"attributes": map[string]string{PathAttribute: "/auth"},
+			"messageId":  "1",
+		},
+	})
+	assert.Nil(t, err)
+	h := http.Header{
+		"Content-Type":   {"application/json"},
+		"Ce-Specversion": {"1.0"},
+		"Ce-Id":          {"1"},
+		"Ce-Source":      {"//pubsub.googleapis.com/projects/bridges/topics/runs"},
+		"Ce-Type":        {"google.cloud.pubsub.topic.v1.messagePublished"},
+	}
+
+	// The bridge named in the message authenticates the event, not the one at the request path
+	code, resp := serveEvent(t, s, "/path", h, event)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, "Missing bearer token", resp.Get("error").String())
+
+	h.Set("Authorization", "Bearer bridge")
+	code, resp = serveEvent(t, s, "/path", h, event)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "hello world", resp.Get("data.key").String())
+}
+
+func TestServer_CloudEventsHandler_CallbackError(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer node.Close()
+	s := NewServer(&LambdaPath{})
+
+	h := http.Header{
+		"Ce-Specversion": {"1.0"},
+		"Ce-Id":          {"1"},
+		"Ce-Source":      {"//chainlink/node"},
+		"Ce-Type":        {"com.chainlink.run"},
+	}
+	code, _ := serveEvent(t, s, "/path", h, []byte(`{"id": "1234", "responseURL": "`+node.URL+`"}`))
+	assert.Equal(t, http.StatusBadGateway, code)
+}
+
+func TestServer_PubSub(t *testing.T) {
+	node, results := newNode(t)
+	defer node.Close()
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+
+	err := s.PubSub(context.Background(), PubSubMessage{
+		Data: []byte(`{"id": "1234", "data": {"symbol": "ETH"}, "responseURL": "` + node.URL + `"}`),
+	})
+	assert.Nil(t, err)
+	result := <-results
+	assert.Equal(t, "ETH", result.Get("data.symbol").String())
+
+	err = s.PubSub(context.Background(), PubSubMessage{
+		Data:       []byte(`{"id": "1234"}`),
+		Attributes: map[string]string{PathAttribute: "/missing"},
+	})
+	assert.EqualError(t, err, `Invalid path "/missing"`)
+}
@@ -4,20 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"github.com/linkpoolio/bridges"
-	"github.com/montanaflynn/stats"
-	"github.com/oliveagle/jsonpath"
-	"net/http"
-	"strconv"
-	"sync"
+	"github.com/linkpoolio/bridges/aggregate"
+	"time"
 )
 
 // Result represents the resulting data returned to Chainlink and
 // merged in `data`
 type Result struct {
-	AggregationType string   `json:"aggregationType"`
-	AggregateValue  string   `json:"aggregateValue"`
-	FailedAPICount  int      `json:"failedApiCount"`
-	APIErrors       []string `json:"apiErrors"`
+	AggregationType string                   `json:"aggregationType"`
+	AggregateValue  string                   `json:"aggregateValue"`
+	FailedAPICount  int                      `json:"failedApiCount"`
+	APIErrors       []string                 `json:"apiErrors"`
+	Sources         []aggregate.SourceResult `json:"sources"`
 }
 
 // APIAggregator is a bridge that allows any public API that return numerical
@@ -25,10 +23,17 @@ type Result struct {
 //  - Mean
 //  - Median
 //  - Mode
+//  - Volume weighted mean (vwap)
+//  - Trimmed mean (trimmedmean)
+//  - Min
+//  - Max
 // To use the bridge:
 //  - `api` []string List of APIs to query
 //  - `paths` []string JSON paths to parse the returning responses
+//  - `volumePaths` []string Optional JSON paths to parse the volumes for vwap
 //  - `type` string Aggregation type to use
+//  - `minResponses` int Optional quorum of valid responses
+//  - `outliers` string Optional outlier rejection, either `mad` or `percent`
 // For example:
 // {
 //    "api": [
@@ -42,44 +47,46 @@ type APIAggregator struct{}
 
 // Run is the bridge.Bridge Run implementation that returns the aggregated result
 func (cc *APIAggregator) Run(h *bridges.Helper) (interface{}, error) {
-	al := len(h.Data.Get("api").Array())
-	pl := len(h.Data.Get("paths").Array())
-
-	var wg sync.WaitGroup
-	wg.Add(al)
-
-	values := make(chan float64, al)
-	errs := make(chan error, al)
-
-	if (al == 0 && pl == 0) || al != pl {
-		return h, errors.New("Invalid api and path array")
+	apis := h.GetStringSliceParam("api")
+	paths := h.GetStringSliceParam("paths")
+	volumePaths := h.GetStringSliceParam("volumePaths")
+	if len(apis) == 0 || len(apis) != len(paths) || (len(volumePaths) > 0 && len(volumePaths) != len(apis)) {
+		return nil, errors.New("Invalid api and path array")
 	}
 
-	p := h.Data.Get("paths").Array()
-	for i, a := range h.Data.Get("api").Array() {
-		go performRequest(h, &wg, a.String(), p[i].String(), values, errs)
+	sources := make([]aggregate.Source, len(apis))
+	for i, a := range apis {
+		var vp string
+		if len(volumePaths) > 0 {
+			vp = volumePaths[i]
+		}
+		sources[i] = aggregate.HTTP(h, a, paths[i], vp, bridges.CallOpts{})
 	}
-	wg.Wait()
-	close(values)
-	close(errs)
 
 	var r Result
 	r.AggregationType = h.GetParam("type")
-	if len(errs) > 0 {
-		r.FailedAPICount = len(errs)
-		var ehAh []string
-		for err := range errs {
-			ehAh = append(ehAh, err.Error())
-		}
-		r.APIErrors = ehAh
+	method := aggregate.Method(r.AggregationType)
+	if !method.Valid() {
+		method = aggregate.Mean
 	}
 
-	if aggValue, eh := aggregateValues(r.AggregationType, values); eh != nil {
-		return nil, fmt.Errorf("Error aggregating value: %s", eh)
-	} else {
-		r.AggregateValue = aggValue
+	ar, err := aggregate.Aggregate(h.Context(), sources, aggregate.Options{
+		Method:       method,
+		Timeout:      10 * time.Second,
+		MinResponses: int(h.GetIntParamOrDefault("minResponses", 1)),
+		Outliers:     aggregate.OutlierMethod(h.GetParam("outliers")),
+	})
+	r.FailedAPICount = ar.Failed
+	r.Sources = ar.Sources
+	for _, s := range ar.Sources {
+		if len(s.Error) > 0 {
+			r.APIErrors = append(r.APIErrors, s.Error)
+		}
 	}
-
+	if err != nil {
+		return nil, fmt.Errorf("Error aggregating value: %s", err)
+	}
+	r.AggregateValue = ar.Result
 	return r, nil
 }
 
@@ -94,62 +101,3 @@ func (cc *APIAggregator) Opts() *bridges.Opts {
 func main() {
 	bridges.NewServer(&APIAggregator{}).Start(8080)
 }
-
-func performRequest(
-	h *bridges.Helper,
-	wg *sync.WaitGroup,
-	api string,
-	path string,
-	values chan<- float64,
-	errs chan<- error,
-) {
-	var obj interface{}
-	defer wg.Done()
-
-	if err := h.HTTPCall(http.MethodGet, api, &obj); err != nil {
-		errs <- err
-		return
-	}
-	val, err := jsonpath.JsonPathLookup(obj, path)
-	if err != nil {
-		errs <- err
-		return
-	}
-	fv, err := strconv.ParseFloat(fmt.Sprint(val), 64)
-	if err != nil {
-		errs <- err
-		return
-	}
-
-	values <- fv
-}
-
-func aggregateValues(aggType string, values chan float64) (string, error) {
-	var av float64
-	var eh error
-	var valAh []float64
-
-	for v := range values {
-		valAh = append(valAh, v)
-	}
-
-	switch aggType {
-	case "mode":
-		var modeAh []float64
-		modeAh, eh = stats.Mode(valAh)
-		if len(modeAh) == 0 {
-			av = valAh[0]
-		} else {
-			av = modeAh[0]
-		}
-		break
-	case "median":
-		av, eh = stats.Median(valAh)
-		break
-	default:
-		av, eh = stats.Mean(valAh)
-		break
-	}
-
-	return fmt.Sprint(av), eh
-}
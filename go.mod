@@ -4,10 +4,11 @@ go 1.12
 
 require (
 	github.com/aws/aws-lambda-go v1.13.2
-	github.com/montanaflynn/stats v0.5.0
-	github.com/oliveagle/jsonpath v0.0.0-20180606110733-2e52cf6e6852
+	github.com/prometheus/client_golang v1.2.1
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.4.0
 	github.com/tidwall/gjson v1.3.2
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
 	gopkg.in/guregu/null.v3 v3.4.0
+	gopkg.in/yaml.v2 v2.2.2
 )
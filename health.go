@@ -0,0 +1,141 @@
+package bridges
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	HealthPath = "/health"
+	ReadyPath  = "/ready"
+
+	// DefaultHealthCheckTimeout is the duration given for the bridge health
+	// checks to finish when the server has no HealthCheckTimeout set
+	DefaultHealthCheckTimeout = 5 * time.Second
+)
+
+// HealthChecker can optionally be implemented by a bridge to report whether
+// it's ready to serve requests, such as pinging its upstream API or confirming
+// an API key is valid. Any checks are ran on each readiness probe.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Health is the JSON response given by the health and readiness endpoints
+type Health struct {
+	Status  string                  `json:"status"`
+	Bridges map[string]BridgeHealth `json:"bridges,omitempty"`
+}
+
+// BridgeHealth is the outcome of a single bridge's health check
+type BridgeHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthHandler responds to liveness probes, always returning ok
+// while the server is able to respond
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, &Health{Status: "ok"})
+}
+
+// ReadyHandler responds to readiness probes, running the health checks of any
+// bridges that implement HealthChecker. If any fail, or the server is shutting
+// down, the server is reported as unavailable.
+func (s *Server) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.healthCheckTimeout())
+	defer cancel()
+
+	h := s.checkHealth(ctx)
+	code := http.StatusOK
+	if h.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, h)
+}
+
+// checkHealth runs the health checks of all the bridges concurrently,
+// aggregating the results
+func (s *Server) checkHealth(ctx context.Context) *Health {
+	h := &Health{Status: "ok", Bridges: make(map[string]BridgeHealth)}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for p, b := range s.pathMap {
+		hc, ok := b.(HealthChecker)
+		if !ok {
+			continue
+		}
+		name := b.Opts().Name
+		if len(name) == 0 {
+			name = p
+		}
+
+		wg.Add(1)
+		go func(name string, hc HealthChecker) {
+			defer wg.Done()
+			bh := BridgeHealth{Status: "ok"}
+			if err := s.runHealthCheck(ctx, name, hc); err != nil {
+				logrus.WithField("bridge", name).Warnf("Health check failed: %v", err)
+				bh = BridgeHealth{Status: "failed", Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			h.Bridges[name] = bh
+			if bh.Status != "ok" {
+				h.Status = "unavailable"
+			}
+		}(name, hc)
+	}
+	wg.Wait()
+
+	if s.isShuttingDown() {
+		h.Status = "unavailable"
+	}
+	return h
+}
+
+// runHealthCheck calls the health check, giving up once the context is done
+// and recovering from any panic
+func (s *Server) runHealthCheck(ctx context.Context, name string, hc HealthChecker) (err error) {
+	ec := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				ec <- s.recovered(p, name)
+			}
+		}()
+		ec <- hc.HealthCheck(ctx)
+	}()
+	select {
+	case err = <-ec:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+func (s *Server) healthCheckTimeout() time.Duration {
+	if s.HealthCheckTimeout > 0 {
+		return s.HealthCheckTimeout
+	}
+	return DefaultHealthCheckTimeout
+}
+
+func (s *Server) isShuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shuttingDown
+}
+
+func writeHealth(w http.ResponseWriter, code int, h *Health) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(h); err != nil {
+		logrus.Errorf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package bridges
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type HealthyBridge struct {
+	HelloWorld
+	err error
+}
+
+func (hb *HealthyBridge) HealthCheck(ctx context.Context) error {
+	return hb.err
+}
+
+func (hb *HealthyBridge) Opts() *Opts {
+	return &Opts{Name: "Healthy", Path: "/healthy"}
+}
+
+type UnhealthyBridge struct {
+	HealthyBridge
+}
+
+func (ub *UnhealthyBridge) Opts() *Opts {
+	return &Opts{Name: "Unhealthy", Path: "/unhealthy"}
+}
+
+type HangingHealthBridge struct {
+	HelloWorld
+}
+
+func (hhb *HangingHealthBridge) HealthCheck(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestServer_Mux_Health(t *testing.T) {
+	mux := NewServer(&UnhealthyBridge{HealthyBridge{err: errors.New("invalid api key")}}).Mux()
+
+	req, err := http.NewRequest(http.MethodGet, HealthPath, nil)
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", json.Get("status").String())
+}
+
+func TestServer_Mux_Ready(t *testing.T) {
+	tests := []struct {
+		name    string
+		bridges []Bridge
+		code    int
+		status  string
+		checks  map[string]string
+	}{
+		{
+			"no health checks",
+			[]Bridge{&HelloWorld{}},
+			http.StatusOK,
+			"ok",
+			map[string]string{},
+		},
+		{
+			"healthy",
+			[]Bridge{&HelloWorld{}, &HealthyBridge{}},
+			http.StatusOK,
+			"ok",
+			map[string]string{"Healthy": "ok"},
+		},
+		{
+			"unhealthy",
+			[]Bridge{&HealthyBridge{}, &UnhealthyBridge{HealthyBridge{err: errors.New("invalid api key")}}},
+			http.StatusServiceUnavailable,
+			"unavailable",
+			map[string]string{"Healthy": "ok", "Unhealthy": "failed"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := NewServer(test.bridges...).Mux()
+
+			req, err := http.NewRequest(http.MethodGet, ReadyPath, nil)
+			assert.Nil(t, err)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			assert.Equal(t, test.code, rr.Code)
+
+			body, err := ioutil.ReadAll(rr.Body)
+			assert.Nil(t, err)
+			json, err := Parse(body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, test.status, json.Get("status").String())
+			assert.Len(t, json.Get("bridges").Map(), len(test.checks))
+			for name, status := range test.checks {
+				assert.Equal(t, status, json.Get("bridges."+name+".status").String())
+			}
+		})
+	}
+}
+
+func TestServer_Ready_Timeout(t *testing.T) {
+	s := NewServer(&HangingHealthBridge{})
+	s.HealthCheckTimeout = 1
+
+	rr := httptest.NewRecorder()
+	s.ReadyHandler(rr, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+	assert.Equal(t, context.DeadlineExceeded.Error(), json.Get("bridges./.error").String())
+}
+
+func TestServer_Ready_ShuttingDown(t *testing.T) {
+	s := NewServer(&HelloWorld{})
+	assert.Nil(t, s.Shutdown(context.Background()))
+
+	rr := httptest.NewRecorder()
+	s.ReadyHandler(rr, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
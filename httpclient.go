@@ -0,0 +1,141 @@
+package bridges
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultUserAgent is the user agent set on upstream calls when none is given
+const DefaultUserAgent = "linkpoolio-bridges"
+
+// DefaultHTTPClient is the http client used by helpers that aren't created by a
+// server, built with the default HTTPClientOpts
+var DefaultHTTPClient, _ = NewHTTPClient(HTTPClientOpts{})
+
+// HTTPClientOpts are the options for building the http client the
+// helpers use to make upstream calls. Zero fields use the defaults.
+type HTTPClientOpts struct {
+	// Timeout is the overall timeout of a single call, defaulting to 30 seconds
+	Timeout time.Duration `json:"timeout"`
+	// MaxIdleConns is the maximum idle connections kept across all hosts,
+	// defaulting to 100
+	MaxIdleConns int `json:"maxIdleConns"`
+	// MaxIdleConnsPerHost is the maximum idle connections kept per host,
+	// defaulting to 10
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+	// IdleConnTimeout is how long idle connections are kept, defaulting to 90 seconds
+	IdleConnTimeout time.Duration `json:"idleConnTimeout"`
+	// Proxy is the URL of the proxy to use, using the proxy set in the
+	// environment when empty
+	Proxy string `json:"proxy"`
+	// CAFile is a PEM bundle of extra certificate authorities to trust
+	CAFile string `json:"caFile"`
+	// CertFile and KeyFile are the client certificate to present to upstreams
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	// UserAgent is set on every call that doesn't already have one
+	UserAgent string `json:"userAgent"`
+	// Transport replaces the transport built from these options, with the
+	// user agent still being set, such as to stub upstreams in tests
+	Transport http.RoundTripper `json:"-"`
+}
+
+// NewHTTPClient returns a http client built from the options, to be shared
+// between helpers so connections are reused across runs
+func NewHTTPClient(opts HTTPClientOpts) (*http.Client, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	ua := opts.UserAgent
+	if len(ua) == 0 {
+		ua = DefaultUserAgent
+	}
+
+	rt := opts.Transport
+	if rt == nil {
+		t, err := newTransport(opts)
+		if err != nil {
+			return nil, err
+		}
+		rt = t
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &userAgentTransport{userAgent: ua, next: rt},
+	}, nil
+}
+
+func newTransport(opts HTTPClientOpts) (*http.Transport, error) {
+	t := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       &tls.Config{},
+	}
+	if opts.MaxIdleConns != 0 {
+		t.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost != 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout != 0 {
+		t.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	if len(opts.Proxy) > 0 {
+		u, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	if len(opts.CAFile) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		b, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("No certificates found in CA file")
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	if len(opts.CertFile) > 0 || len(opts.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	return t, nil
+}
+
+// userAgentTransport sets the user agent on any request without one
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (uat *userAgentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if len(r.Header.Get("User-Agent")) == 0 {
+		r = r.Clone(r.Context())
+		r.Header.Set("User-Agent", uat.userAgent)
+	}
+	return uat.next.RoundTrip(r)
+}
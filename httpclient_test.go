@@ -0,0 +1,136 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func stubTransport(body string) http.RoundTripper {
+	return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-User-Agent": []string{r.Header.Get("User-Agent")}},
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			Request:    r,
+		}, nil
+	})
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	c, err := NewHTTPClient(HTTPClientOpts{})
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, c.Timeout)
+
+	tr := c.Transport.(*userAgentTransport)
+	assert.Equal(t, DefaultUserAgent, tr.userAgent)
+	ht := tr.next.(*http.Transport)
+	assert.Equal(t, 100, ht.MaxIdleConns)
+	assert.Equal(t, 10, ht.MaxIdleConnsPerHost)
+
+	c, err = NewHTTPClient(HTTPClientOpts{
+		Timeout:             time.Second,
+		MaxIdleConnsPerHost: 50,
+		Proxy:               "http://proxy:3128",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, c.Timeout)
+	ht = c.Transport.(*userAgentTransport).next.(*http.Transport)
+	assert.Equal(t, 50, ht.MaxIdleConnsPerHost)
+
+	req, err := http.NewRequest(http.MethodGet, "http://upstream", nil)
+	assert.Nil(t, err)
+	u, err := ht.Proxy(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "proxy:3128", u.Host)
+}
+
+func TestNewHTTPClient_InvalidFiles(t *testing.T) {
+	_, err := NewHTTPClient(HTTPClientOpts{CAFile: "nonexistent.pem"})
+	assert.NotNil(t, err)
+
+	_, err = NewHTTPClient(HTTPClientOpts{CertFile: "nonexistent.crt", KeyFile: "nonexistent.key"})
+	assert.NotNil(t, err)
+}
+
+func TestHelper_WithHTTPClient(t *testing.T) {
+	c, err := NewHTTPClient(HTTPClientOpts{
+		UserAgent: "test-agent",
+		Transport: stubTransport(`{"price":1}`),
+	})
+	assert.Nil(t, err)
+
+	r := make(map[string]interface{})
+	err = NewHelper(nil).WithHTTPClient(c).HTTPCall(http.MethodGet, "http://upstream", &r)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), r["price"])
+}
+
+type ClientOverride struct {
+	Upstream
+	client *http.Client
+}
+
+func (co *ClientOverride) Opts() *Opts {
+	return &Opts{Name: "ClientOverride", HTTPClient: co.client}
+}
+
+func TestServer_Mux_HTTPClient(t *testing.T) {
+	server, err := NewHTTPClient(HTTPClientOpts{Transport: stubTransport(`{"client":"server"}`)})
+	assert.Nil(t, err)
+	bridge, err := NewHTTPClient(HTTPClientOpts{Transport: stubTransport(`{"client":"bridge"}`)})
+	assert.Nil(t, err)
+
+	s := NewServer(
+		&Upstream{url: "http://upstream"},
+		&ClientOverride{Upstream{url: "http://upstream"}, bridge},
+	)
+	s.HTTPClient = server
+	mux := s.Mux()
+
+	for p, client := range map[string]string{"/upstream": "server", "/": "bridge"} {
+		pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+		assert.Nil(t, err)
+		req, err := http.NewRequest(http.MethodPost, p, bytes.NewReader(pb))
+		assert.Nil(t, err)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		body, err := ioutil.ReadAll(rr.Body)
+		assert.Nil(t, err)
+		json, err := Parse(body)
+		assert.Nil(t, err)
+		assert.Equal(t, client, json.Get("data.client").String())
+	}
+}
+
+func TestUserAgentTransport(t *testing.T) {
+	c, err := NewHTTPClient(HTTPClientOpts{
+		UserAgent: "test-agent",
+		Transport: stubTransport(`{}`),
+	})
+	assert.Nil(t, err)
+
+	resp, err := c.Get("http://upstream")
+	assert.Nil(t, err)
+	assert.Equal(t, "test-agent", resp.Header.Get("X-User-Agent"))
+
+	req, err := http.NewRequest(http.MethodGet, "http://upstream", nil)
+	assert.Nil(t, err)
+	req.Header.Set("User-Agent", "custom")
+	resp, err = c.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "custom", resp.Header.Get("X-User-Agent"))
+}
@@ -0,0 +1,141 @@
+package bridges
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long results are kept by a
+// MemoryIdempotencyStore when no TTL is given
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// StoredResult is a result stored for a job run, along with
+// the status code it was responded with
+type StoredResult struct {
+	Code   int    `json:"code"`
+	Result Result `json:"result"`
+}
+
+// IdempotencyStore is the generic interface for storing the results of job runs,
+// so that retried requests from the node are given the stored result instead of
+// the bridge being ran again. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	Get(key string) (*StoredResult, bool)
+	Set(key string, r *StoredResult)
+}
+
+// MemoryIdempotencyStore is the in-memory IdempotencyStore implementation,
+// expiring results once their TTL has passed
+type MemoryIdempotencyStore struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	results   map[string]memoryStoredResult
+	lastSweep time.Time
+}
+
+type memoryStoredResult struct {
+	result    *StoredResult
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore returns a MemoryIdempotencyStore keeping
+// results for the given TTL
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &MemoryIdempotencyStore{
+		ttl:       ttl,
+		results:   make(map[string]memoryStoredResult),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns the result stored for the key, if it hasn't expired
+func (m *MemoryIdempotencyStore) Get(key string) (*StoredResult, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.results[key]
+	if !ok || time.Now().After(r.expiresAt) {
+		return nil, false
+	}
+	return r.result, true
+}
+
+// Set stores the result for the key, removing any expired results
+// at most once per TTL
+func (m *MemoryIdempotencyStore) Set(key string, r *StoredResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) > m.ttl {
+		for k, v := range m.results {
+			if now.After(v.expiresAt) {
+				delete(m.results, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.results[key] = memoryStoredResult{result: r, expiresAt: now.Add(m.ttl)}
+}
+
+// idempotencyKey returns the key the result of the run is stored with, made
+// from the bridge path, job run ID and task run ID if given. If there's no
+// store or job run ID, then false is returned.
+func (s *Server) idempotencyKey(b Bridge, rt *Result) (string, bool) {
+	if s.IdempotencyStore == nil || len(rt.JobRunID) == 0 {
+		return "", false
+	}
+	p := b.Opts().Path
+	if len(p) == 0 {
+		p = "/"
+	}
+	key := p + ":" + rt.JobRunID
+	if len(rt.TaskRunID) > 0 {
+		key += ":" + rt.TaskRunID
+	}
+	return key, true
+}
+
+// runIdempotent executes the run, unless a result is already stored for the
+// job run where it's given instead. Concurrent duplicate runs wait for the first
+// to finish and are given its result.
+//
+// Errored results with a server error status code, such as timeouts, aren't
+// stored so that retries from the node are ran again. Async runs store their
+// own pending and final results.
+func (s *Server) runIdempotent(b Bridge, rt *Result, exec func(rt *Result) int) int {
+	key, ok := s.idempotencyKey(b, rt)
+	if !ok {
+		return exec(rt)
+	}
+	if sr, ok := s.IdempotencyStore.Get(key); ok {
+		*rt = sr.Result
+		return sr.Code
+	}
+
+	v, _, _ := s.runs.Do(key, func() (interface{}, error) {
+		if sr, ok := s.IdempotencyStore.Get(key); ok {
+			return sr, nil
+		}
+		r := *rt
+		sr := &StoredResult{Code: exec(&r), Result: r}
+		if !r.Pending {
+			s.storeResult(key, sr)
+		}
+		return sr, nil
+	})
+	sr := v.(*StoredResult)
+	*rt = sr.Result
+	return sr.Code
+}
+
+// storeResult stores the result unless it errored with a server error
+func (s *Server) storeResult(key string, sr *StoredResult) {
+	if sr.Code >= 500 {
+		return
+	}
+	s.IdempotencyStore.Set(key, sr)
+}
@@ -0,0 +1,176 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Counter struct {
+	runs    int32
+	err     error
+	release chan struct{}
+}
+
+func (c *Counter) Run(h *Helper) (interface{}, error) {
+	n := atomic.AddInt32(&c.runs, 1)
+	if c.release != nil {
+		<-c.release
+	}
+	return map[string]int32{"run": n}, c.err
+}
+
+func (c *Counter) Opts() *Opts {
+	return &Opts{Name: "Counter", Lambda: true}
+}
+
+func postRun(t *testing.T, h http.Handler, in map[string]interface{}) (int, *JSON) {
+	return postRunPath(t, h, "/", in)
+}
+
+func postRunPath(t *testing.T, h http.Handler, path string, in map[string]interface{}) (int, *JSON) {
+	pb, err := json.Marshal(in)
+	assert.Nil(t, err)
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(pb))
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	json, err := Parse(body)
+	assert.Nil(t, err)
+	return rr.Code, json
+}
+
+func TestServer_Mux_Idempotent(t *testing.T) {
+	b := &Counter{}
+	s := NewServer(b)
+	s.IdempotencyStore = NewMemoryIdempotencyStore(time.Minute)
+	mux := s.Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(1), json.Get("data.run").Int())
+
+	code, json = postRun(t, mux, map[string]interface{}{"jobRunId": "1234"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1234", json.Get("jobRunId").String())
+	assert.Equal(t, int64(1), json.Get("data.run").Int())
+
+	code, json = postRun(t, mux, map[string]interface{}{"id": "1234", "taskRunId": "1"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(2), json.Get("data.run").Int())
+
+	code, json = postRun(t, mux, map[string]interface{}{"id": "4321"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), json.Get("data.run").Int())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&b.runs))
+}
+
+func TestServer_Mux_IdempotentConcurrent(t *testing.T) {
+	b := &Counter{release: make(chan struct{})}
+	s := NewServer(b)
+	s.IdempotencyStore = NewMemoryIdempotencyStore(time.Minute)
+	mux := s.Mux()
+
+	var wg sync.WaitGroup
+	runs := make([]int64, 3)
+	for i := range runs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+			runs[i] = json.Get("data.run").Int()
+		}(i)
+	}
+
+	// Give the duplicates time to be waiting before the run finishes
+	time.Sleep(100 * time.Millisecond)
+	close(b.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.runs))
+	assert.Equal(t, []int64{1, 1, 1}, runs)
+}
+
+func TestServer_Mux_IdempotentErrored(t *testing.T) {
+	b := &Counter{err: errors.New("upstream down")}
+	s := NewServer(b)
+	s.IdempotencyStore = NewMemoryIdempotencyStore(time.Minute)
+	mux := s.Mux()
+
+	for i := 0; i < 2; i++ {
+		code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+		assert.Equal(t, http.StatusInternalServerError, code)
+		assert.Equal(t, "errored", json.Get("status").String())
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&b.runs))
+}
+
+func TestServer_Lambda_Idempotent(t *testing.T) {
+	b := &Counter{}
+	s := NewServer(b)
+	s.IdempotencyStore = NewMemoryIdempotencyStore(time.Minute)
+
+	for i := 0; i < 2; i++ {
+		r := &Result{ID: "1234"}
+		_, err := s.Lambda(r)
+		assert.Nil(t, err)
+		assert.Equal(t, "completed", r.Status)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.runs))
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	m := NewMemoryIdempotencyStore(50 * time.Millisecond)
+	m.Set("a", &StoredResult{Code: http.StatusOK, Result: Result{JobRunID: "a"}})
+
+	sr, ok := m.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "a", sr.Result.JobRunID)
+	_, ok = m.Get("b")
+	assert.False(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	_, ok = m.Get("a")
+	assert.False(t, ok)
+
+	m.Set("b", &StoredResult{Code: http.StatusOK})
+	assert.Len(t, m.results, 1)
+}
+
+func TestServer_Mux_IdempotentAsync(t *testing.T) {
+	called := make(chan struct{}, 1)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+	}))
+	defer node.Close()
+
+	s := NewServer(&AsyncHelloWorld{})
+	s.IdempotencyStore = NewMemoryIdempotencyStore(time.Minute)
+	mux := s.Mux()
+	in := map[string]interface{}{"id": "1234", "responseURL": node.URL}
+
+	_, json := postRun(t, mux, in)
+	assert.Equal(t, "pending", json.Get("status").String())
+
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the async callback")
+	}
+	s.pending.Wait()
+
+	_, json = postRun(t, mux, in)
+	assert.Equal(t, "completed", json.Get("status").String())
+	assert.Equal(t, "hello world", json.Get("data.key").String())
+}
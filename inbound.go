@@ -0,0 +1,164 @@
+package bridges
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultSignatureHeader is the header HMACSignature reads the
+// request signature from when no header is given
+const DefaultSignatureHeader = "X-Chainlink-Signature"
+
+// InboundAuth is the generic interface for authenticating the requests
+// received from the Chainlink node. Verify is given the request and its
+// raw body, returning an error if the request shouldn't be served.
+type InboundAuth interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+// authenticate verifies the request against the InboundAuth of the bridge
+// mounted on the requested path, falling back to the server's InboundAuth
+func (s *Server) authenticate(r *http.Request, body []byte) error {
+	return s.authenticateBridge(s.pathMap[s.path(r)], r, body)
+}
+
+// authenticateBridge verifies the request against the InboundAuth of the
+// bridge, falling back to the server's InboundAuth if it's nil or has none
+func (s *Server) authenticateBridge(b Bridge, r *http.Request, body []byte) error {
+	a := s.InboundAuth
+	if b != nil && s.opts(b).InboundAuth != nil {
+		a = s.opts(b).InboundAuth
+	}
+	if a == nil {
+		return nil
+	}
+	return a.Verify(r, body)
+}
+
+// BearerToken is the InboundAuth implementation that matches the
+// `Authorization: Bearer` header against the node's outgoing token
+type BearerToken struct {
+	Token string
+}
+
+// Verify checks the bearer token given in the request matches
+func (bt *BearerToken) Verify(r *http.Request, _ []byte) error {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return errors.New("Missing bearer token")
+	}
+	t := strings.TrimPrefix(h, "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(t), []byte(bt.Token)) != 1 {
+		return errors.New("Invalid bearer token")
+	}
+	return nil
+}
+
+// HMACSignature is the InboundAuth implementation that requires the request
+// body to be signed with a shared secret using HMAC-SHA256, with the hex
+// encoded signature set in the header.
+type HMACSignature struct {
+	Secret string
+	Header string
+}
+
+// Verify computes the signature of the body and compares it with the
+// signature given in the header, with or without a `sha256=` prefix
+func (hs *HMACSignature) Verify(r *http.Request, body []byte) error {
+	header := hs.Header
+	if len(header) == 0 {
+		header = DefaultSignatureHeader
+	}
+	sig := strings.TrimPrefix(r.Header.Get(header), "sha256=")
+	if len(sig) == 0 {
+		return errors.New("Missing request signature")
+	}
+	given, err := hex.DecodeString(sig)
+	if err != nil {
+		return errors.New("Invalid request signature")
+	}
+	if !hmac.Equal(given, hs.Sign(body)) {
+		return errors.New("Invalid request signature")
+	}
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 of the body using the shared secret
+func (hs *HMACSignature) Sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(hs.Secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// ClientCertAllowlist is the InboundAuth implementation that only allows
+// requests with a verified TLS client certificate, matching either the
+// certificate's common name or its hex encoded SHA-256 fingerprint.
+//
+// The server must be served over TLS with client certificates requested
+// for this to be of any use. The certificate must have been verified by the
+// server, such as with TLSConfig.ClientCAFile, unless Roots is set.
+type ClientCertAllowlist struct {
+	CommonNames  []string
+	Fingerprints []string
+	// Roots verifies the client certificate if set, for servers that
+	// request client certificates without verifying them
+	Roots *x509.CertPool
+}
+
+// Verify checks the verified leaf client certificate against the allowlist
+func (ca *ClientCertAllowlist) Verify(r *http.Request, _ []byte) error {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return errors.New("Missing client certificate")
+	}
+	cert, err := ca.verifiedLeaf(r.TLS)
+	if err != nil {
+		return err
+	}
+	for _, cn := range ca.CommonNames {
+		if cert.Subject.CommonName == cn {
+			return nil
+		}
+	}
+	sum := sha256.Sum256(cert.Raw)
+	fp := hex.EncodeToString(sum[:])
+	for _, f := range ca.Fingerprints {
+		if strings.EqualFold(strings.Replace(f, ":", "", -1), fp) {
+			return nil
+		}
+	}
+	return errors.New("Client certificate not allowed")
+}
+
+// verifiedLeaf returns the leaf client certificate, verified against Roots
+// if set or otherwise by the server
+func (ca *ClientCertAllowlist) verifiedLeaf(cs *tls.ConnectionState) (*x509.Certificate, error) {
+	if ca.Roots == nil {
+		if len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+			return nil, errors.New("Client certificate not verified")
+		}
+		return cs.VerifiedChains[0][0], nil
+	}
+
+	leaf := cs.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         ca.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Invalid client certificate: %v", err)
+	}
+	return leaf, nil
+}
@@ -0,0 +1,138 @@
+package bridges
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type AuthHelloWorld struct{}
+
+func (ahw *AuthHelloWorld) Run(h *Helper) (interface{}, error) {
+	return map[string]string{"key": "hello world"}, nil
+}
+
+func (ahw *AuthHelloWorld) Opts() *Opts {
+	return &Opts{
+		Path:        "/auth",
+		InboundAuth: &BearerToken{Token: "bridge"},
+	}
+}
+
+func TestServer_Mux_InboundAuth(t *testing.T) {
+	s := NewServer(&HelloWorld{}, &AuthHelloWorld{})
+	s.InboundAuth = &BearerToken{Token: "server"}
+	mux := s.Mux()
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		code  int
+	}{
+		{"server token", "/", "server", http.StatusOK},
+		{"invalid server token", "/", "bridge", http.StatusUnauthorized},
+		{"missing token", "/", "", http.StatusUnauthorized},
+		{"bridge token", "/auth", "bridge", http.StatusOK},
+		{"invalid bridge token", "/auth", "server", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+			assert.Nil(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, test.path, bytes.NewReader(pb))
+			assert.Nil(t, err)
+			if len(test.token) > 0 {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+			assert.Equal(t, test.code, rr.Code)
+
+			body, err := ioutil.ReadAll(rr.Body)
+			assert.Nil(t, err)
+			json, err := Parse(body)
+			assert.Nil(t, err)
+			if test.code == http.StatusOK {
+				assert.Equal(t, "completed", json.Get("status").String())
+			} else {
+				assert.Equal(t, "errored", json.Get("status").String())
+			}
+		})
+	}
+}
+
+func TestHMACSignature_Verify(t *testing.T) {
+	hs := &HMACSignature{Secret: "secret"}
+	body := []byte(`{"id":"1234"}`)
+	sig := hex.EncodeToString(hs.Sign(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.EqualError(t, hs.Verify(req, body), "Missing request signature")
+
+	req.Header.Set(DefaultSignatureHeader, sig)
+	assert.Nil(t, hs.Verify(req, body))
+
+	req.Header.Set(DefaultSignatureHeader, "sha256="+sig)
+	assert.Nil(t, hs.Verify(req, body))
+
+	assert.EqualError(t, hs.Verify(req, []byte(`{"id":"4321"}`)), "Invalid request signature")
+
+	req.Header.Set(DefaultSignatureHeader, "not hex")
+	assert.EqualError(t, hs.Verify(req, body), "Invalid request signature")
+}
+
+func TestClientCertAllowlist_Verify(t *testing.T) {
+	cert := &x509.Certificate{
+		Raw:     []byte("certificate"),
+		Subject: pkix.Name{CommonName: "node"},
+	}
+	sum := sha256.Sum256(cert.Raw)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	assert.EqualError(t, (&ClientCertAllowlist{}).Verify(req, nil), "Missing client certificate")
+
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	assert.EqualError(
+		t,
+		(&ClientCertAllowlist{CommonNames: []string{"node"}}).Verify(req, nil),
+		"Client certificate not verified",
+	)
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	assert.Nil(t, (&ClientCertAllowlist{CommonNames: []string{"node"}}).Verify(req, nil))
+	assert.Nil(t, (&ClientCertAllowlist{Fingerprints: []string{hex.EncodeToString(sum[:])}}).Verify(req, nil))
+	assert.EqualError(
+		t,
+		(&ClientCertAllowlist{CommonNames: []string{"other"}}).Verify(req, nil),
+		"Client certificate not allowed",
+	)
+}
+
+func TestClientCertAllowlist_Verify_Roots(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	allowlist := &ClientCertAllowlist{CommonNames: []string{"node"}, Roots: roots}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newTestCert(t, "node", ca).cert}}
+	assert.Nil(t, allowlist.Verify(req, nil))
+
+	// A self-signed certificate with an allowed common name isn't trusted
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newTestCert(t, "node", nil).cert}}
+	err := allowlist.Verify(req, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Invalid client certificate")
+}
@@ -0,0 +1,271 @@
+package bridges
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var errNoLambdaBridge = errors.New("No bridge has Lambda enabled")
+
+// httpAPIRequest is an API Gateway HTTP API proxy event with the 2.0 payload
+// format, which isn't in the events package of aws-lambda-go v1.13
+type httpAPIRequest struct {
+	Version         string            `json:"version"`
+	RawPath         string            `json:"rawPath"`
+	RawQueryString  string            `json:"rawQueryString"`
+	Cookies         []string          `json:"cookies"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+	RequestContext  struct {
+		HTTP struct {
+			Method   string `json:"method"`
+			SourceIP string `json:"sourceIp"`
+		} `json:"http"`
+	} `json:"requestContext"`
+}
+
+// httpAPIResponse is the response to an httpAPIRequest
+type httpAPIResponse struct {
+	StatusCode      int               `json:"statusCode"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Cookies         []string          `json:"cookies,omitempty"`
+	Body            string            `json:"body"`
+	IsBase64Encoded bool              `json:"isBase64Encoded"`
+}
+
+// LambdaEvent is the handler for AWS Lambda used by Start. API Gateway REST
+// and HTTP API, and ALB proxy events are routed by their path to any of the
+// bridges, the same as with the inbuilt http server. Any other event is taken
+// as the Result to run the Lambda bridge with, as LambdaWithContext.
+func (s *Server) LambdaEvent(ctx context.Context, event json.RawMessage) (interface{}, error) {
+	var e struct {
+		Version        string `json:"version"`
+		HTTPMethod     string `json:"httpMethod"`
+		RequestContext struct {
+			ELB  *events.ELBContext `json:"elb"`
+			HTTP *json.RawMessage   `json:"http"`
+		} `json:"requestContext"`
+	}
+	if err := json.Unmarshal(event, &e); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case e.RequestContext.HTTP != nil && e.Version == "2.0":
+		var req httpAPIRequest
+		if err := json.Unmarshal(event, &req); err != nil {
+			return nil, err
+		}
+		return s.httpAPI(ctx, &req)
+	case e.RequestContext.ELB != nil:
+		var req events.ALBTargetGroupRequest
+		if err := json.Unmarshal(event, &req); err != nil {
+			return nil, err
+		}
+		return s.alb(ctx, &req)
+	case len(e.HTTPMethod) > 0:
+		var req events.APIGatewayProxyRequest
+		if err := json.Unmarshal(event, &req); err != nil {
+			return nil, err
+		}
+		return s.apiGateway(ctx, &req)
+	}
+
+	var r Result
+	if err := json.Unmarshal(event, &r); err != nil {
+		return nil, err
+	}
+	return s.LambdaWithContext(ctx, &r)
+}
+
+func (s *Server) apiGateway(ctx context.Context, e *events.APIGatewayProxyRequest) (interface{}, error) {
+	q := url.Values(e.MultiValueQueryStringParameters)
+	if len(q) == 0 {
+		q = url.Values{}
+		for k, v := range e.QueryStringParameters {
+			q.Set(k, v)
+		}
+	}
+	h := proxyHeader(e.Headers, e.MultiValueHeaders)
+	w, err := s.serveProxy(ctx, e.HTTPMethod, e.Path, q.Encode(), h, e.Body, e.IsBase64Encoded, e.RequestContext.Identity.SourceIP)
+	if err != nil {
+		return nil, err
+	}
+	body, b64 := w.encodedBody()
+	return &events.APIGatewayProxyResponse{
+		StatusCode:        w.statusCode(),
+		Headers:           map[string]string{},
+		MultiValueHeaders: w.header,
+		Body:              body,
+		IsBase64Encoded:   b64,
+	}, nil
+}
+
+func (s *Server) httpAPI(ctx context.Context, e *httpAPIRequest) (interface{}, error) {
+	h := proxyHeader(e.Headers, nil)
+	if len(e.Cookies) > 0 {
+		h.Set("Cookie", strings.Join(e.Cookies, "; "))
+	}
+	rc := e.RequestContext.HTTP
+	w, err := s.serveProxy(ctx, rc.Method, e.RawPath, e.RawQueryString, h, e.Body, e.IsBase64Encoded, rc.SourceIP)
+	if err != nil {
+		return nil, err
+	}
+	body, b64 := w.encodedBody()
+	resp := &httpAPIResponse{
+		StatusCode:      w.statusCode(),
+		Headers:         map[string]string{},
+		Cookies:         w.header["Set-Cookie"],
+		Body:            body,
+		IsBase64Encoded: b64,
+	}
+	for k, v := range w.header {
+		if k != "Set-Cookie" {
+			resp.Headers[k] = strings.Join(v, ",")
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) alb(ctx context.Context, e *events.ALBTargetGroupRequest) (interface{}, error) {
+	// The ALB gives query parameters as they were sent, without decoding them
+	var q []string
+	if len(e.MultiValueQueryStringParameters) > 0 {
+		for k, vs := range e.MultiValueQueryStringParameters {
+			for _, v := range vs {
+				q = append(q, k+"="+v)
+			}
+		}
+	} else {
+		for k, v := range e.QueryStringParameters {
+			q = append(q, k+"="+v)
+		}
+	}
+	sort.Strings(q)
+	h := proxyHeader(e.Headers, e.MultiValueHeaders)
+	w, err := s.serveProxy(ctx, e.HTTPMethod, e.Path, strings.Join(q, "&"), h, e.Body, e.IsBase64Encoded, h.Get("X-Forwarded-For"))
+	if err != nil {
+		return nil, err
+	}
+	body, b64 := w.encodedBody()
+	code := w.statusCode()
+	resp := &events.ALBTargetGroupResponse{
+		StatusCode:        code,
+		StatusDescription: fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		Body:              body,
+		IsBase64Encoded:   b64,
+	}
+	// Multi-value headers must be responded with if the target group has them enabled
+	if e.MultiValueHeaders != nil {
+		resp.MultiValueHeaders = w.header
+	} else {
+		resp.Headers = map[string]string{}
+		for k, v := range w.header {
+			resp.Headers[k] = strings.Join(v, ",")
+		}
+	}
+	return resp, nil
+}
+
+// serveProxy serves the request of a proxy event with the Mux
+func (s *Server) serveProxy(ctx context.Context, method, path, query string, h http.Header, body string, b64 bool, remoteAddr string) (*proxyResponseWriter, error) {
+	b := []byte(body)
+	if b64 {
+		var err error
+		if b, err = base64.StdEncoding.DecodeString(body); err != nil {
+			return nil, err
+		}
+	}
+	if len(path) == 0 {
+		path = "/"
+	}
+	u := &url.URL{Path: path, RawQuery: query}
+	r, err := http.NewRequest(method, u.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	r = r.WithContext(context.WithValue(ctx, proxyEventKey{}, true))
+	r.Header = h
+	r.Host = h.Get("Host")
+	r.RemoteAddr = remoteAddr
+
+	w := &proxyResponseWriter{header: http.Header{}}
+	s.ServeHTTP(w, r)
+	return w, nil
+}
+
+// proxyEventKey is the context key marking requests served from proxy events
+type proxyEventKey struct{}
+
+func isProxyEvent(ctx context.Context) bool {
+	ok, _ := ctx.Value(proxyEventKey{}).(bool)
+	return ok
+}
+
+// proxyHeader returns the header of a proxy event, preferring the
+// multi-value headers if given
+func proxyHeader(single map[string]string, multi map[string][]string) http.Header {
+	h := http.Header{}
+	if len(multi) > 0 {
+		for k, vs := range multi {
+			for _, v := range vs {
+				h.Add(k, v)
+			}
+		}
+		return h
+	}
+	for k, v := range single {
+		h.Set(k, v)
+	}
+	return h
+}
+
+// proxyResponseWriter buffers the response to a proxy event
+type proxyResponseWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	code   int
+}
+
+func (w *proxyResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *proxyResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *proxyResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *proxyResponseWriter) statusCode() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// encodedBody returns the body as is if it's text, otherwise base64 encoded
+func (w *proxyResponseWriter) encodedBody() (string, bool) {
+	if utf8.Valid(w.body.Bytes()) {
+		return w.body.String(), false
+	}
+	return base64.StdEncoding.EncodeToString(w.body.Bytes()), true
+}
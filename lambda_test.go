@@ -0,0 +1,125 @@
+package bridges
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func lambdaEvent(t *testing.T, s *Server, event interface{}) *JSON {
+	e, err := json.Marshal(event)
+	assert.Nil(t, err)
+	resp, err := s.LambdaEvent(context.Background(), e)
+	assert.Nil(t, err)
+	b, err := json.Marshal(resp)
+	assert.Nil(t, err)
+	j, err := Parse(b)
+	assert.Nil(t, err)
+	return j
+}
+
+func TestServer_LambdaEvent_APIGateway(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+
+	resp := lambdaEvent(t, s, events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/path",
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       `{"id": "1234"}`,
+	})
+	assert.Equal(t, int64(http.StatusOK), resp.Get("statusCode").Int())
+	assert.Equal(t, "application/json", resp.Get("multiValueHeaders.Content-Type.0").String())
+	body := JSON{}
+	assert.Nil(t, json.Unmarshal([]byte(resp.Get("body").String()), &body))
+	assert.Equal(t, "1234", body.Get("jobRunId").String())
+	assert.Equal(t, "hello world", body.Get("data.key").String())
+
+	resp = lambdaEvent(t, s, events.APIGatewayProxyRequest{
+		HTTPMethod:      http.MethodPost,
+		Path:            "/",
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"id": "1234", "data": {"symbol": "ETH"}}`)),
+		IsBase64Encoded: true,
+	})
+	assert.Equal(t, int64(http.StatusOK), resp.Get("statusCode").Int())
+	assert.Nil(t, json.Unmarshal([]byte(resp.Get("body").String()), &body))
+	assert.Equal(t, "ETH", body.Get("data.symbol").String())
+}
+
+func TestServer_LambdaEvent_HTTPAPI(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+
+	event := httpAPIRequest{Version: "2.0", RawPath: "/", Body: `{"id": "1234"}`}
+	event.RequestContext.HTTP.Method = http.MethodPost
+	resp := lambdaEvent(t, s, event)
+	assert.Equal(t, int64(http.StatusBadRequest), resp.Get("statusCode").Int())
+	assert.Equal(t, "application/json", resp.Get("headers.Content-Type").String())
+	body := JSON{}
+	assert.Nil(t, json.Unmarshal([]byte(resp.Get("body").String()), &body))
+	assert.Equal(t, "errored", body.Get("status").String())
+
+	event = httpAPIRequest{Version: "2.0", RawPath: HealthPath}
+	event.RequestContext.HTTP.Method = http.MethodGet
+	resp = lambdaEvent(t, s, event)
+	assert.Equal(t, int64(http.StatusOK), resp.Get("statusCode").Int())
+}
+
+func TestServer_LambdaEvent_ALB(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+
+	event := events.ALBTargetGroupRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/path",
+		Body:       `{"id": "1234"}`,
+	}
+	event.RequestContext.ELB.TargetGroupArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/bridges"
+	resp := lambdaEvent(t, s, event)
+	assert.Equal(t, int64(http.StatusOK), resp.Get("statusCode").Int())
+	assert.Equal(t, "200 OK", resp.Get("statusDescription").String())
+	assert.Equal(t, "application/json", resp.Get("headers.Content-Type").String())
+	assert.False(t, resp.Get("multiValueHeaders.Content-Type").Exists())
+
+	event.MultiValueHeaders = map[string][]string{"Content-Type": {"application/json"}}
+	resp = lambdaEvent(t, s, event)
+	assert.Equal(t, "application/json", resp.Get("multiValueHeaders.Content-Type.0").String())
+}
+
+func TestServer_LambdaEvent_Result(t *testing.T) {
+	s := NewServer(&LambdaPath{}, &RequireParam{})
+	resp := lambdaEvent(t, s, map[string]interface{}{"id": "1234"})
+	assert.Equal(t, "1234", resp.Get("jobRunId").String())
+	assert.Equal(t, "hello world", resp.Get("data.key").String())
+
+	s = NewServer(&RequireParam{})
+	_, err := s.LambdaEvent(context.Background(), json.RawMessage(`{"id": "1234"}`))
+	assert.Equal(t, errNoLambdaBridge, err)
+}
+
+func TestServer_LambdaEvent_Async(t *testing.T) {
+	var callbacks int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&callbacks, 1)
+	}))
+	defer node.Close()
+
+	s := NewServer(&AsyncHelloWorld{})
+	resp := lambdaEvent(t, s, events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/",
+		Body:       `{"id": "1234", "responseURL": "` + node.URL + `/v2/runs/1234"}`,
+	})
+	assert.Equal(t, int64(http.StatusOK), resp.Get("statusCode").Int())
+	body := JSON{}
+	assert.Nil(t, json.Unmarshal([]byte(resp.Get("body").String()), &body))
+	assert.Equal(t, "completed", body.Get("status").String())
+	assert.Equal(t, "hello world", body.Get("data.key").String())
+
+	// The node is given the result exactly once, in the response
+	s.pending.Wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&callbacks))
+}
@@ -0,0 +1,180 @@
+package bridges
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultShutdownTimeout is the duration given for in-flight requests to
+// finish on shutdown when the server has no ShutdownTimeout set
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Initializer can optionally be implemented by a bridge to open any resources it
+// needs, such as websocket feeds or database pools, before requests are served.
+//
+// Bridges can also implement io.Closer to release those resources on shutdown.
+type Initializer interface {
+	Init(ctx context.Context) error
+}
+
+// Run initialises the bridges and starts the inbuilt http server on Addr,
+// served over HTTPS if TLS is set, blocking until the context is done. The
+// server is then gracefully shut down, giving in-flight requests until
+// ShutdownTimeout to finish.
+func (s *Server) Run(ctx context.Context) error {
+	return s.runHandler(ctx, s.Mux())
+}
+
+// runHandler mirrors Run, serving requests with the given handler
+func (s *Server) runHandler(ctx context.Context, h http.Handler) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(ctx, ln, h)
+}
+
+// serve mirrors runHandler, serving requests from the given listener
+func (s *Server) serve(ctx context.Context, ln net.Listener, h http.Handler) error {
+	if err := s.checkPaths(); err != nil {
+		ln.Close()
+		return err
+	}
+	if s.TLS != nil {
+		tc, err := s.TLS.Load()
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		ln = tls.NewListener(ln, tc)
+	}
+	if err := s.initBridges(ctx); err != nil {
+		ln.Close()
+		return err
+	}
+
+	srv := &http.Server{Handler: h}
+	s.mu.Lock()
+	s.srv = srv
+	s.mu.Unlock()
+
+	ec := make(chan error, 1)
+	go func() {
+		ec <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-ec:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		s.closeBridges()
+		return err
+	case <-ctx.Done():
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+	defer cancel()
+	return s.Shutdown(sctx)
+}
+
+// Shutdown gracefully stops the inbuilt http server, waiting for in-flight
+// requests and async runs to finish until the context is done, then
+// closes any bridges that implement io.Closer.
+//
+// Readiness probes fail as soon as it's called, with new requests still
+// being served for the DrainDelay before the server stops accepting them.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.srv
+	s.shuttingDown = true
+	s.mu.Unlock()
+
+	var err error
+	if srv != nil {
+		if s.DrainDelay > 0 {
+			logrus.WithField("delay", s.DrainDelay).Info("Draining the bridge server")
+			select {
+			case <-time.After(s.DrainDelay):
+			case <-ctx.Done():
+			}
+		}
+		err = srv.Shutdown(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	if cerr := s.closeBridges(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *Server) shutdownTimeout() time.Duration {
+	if s.ShutdownTimeout > 0 {
+		return s.ShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
+
+// initBridges calls Init on every bridge that implements Initializer. If
+// any fail, the bridges initialised so far are closed.
+func (s *Server) initBridges(ctx context.Context) error {
+	var inited []Bridge
+	for _, b := range s.pathMap {
+		if i, ok := b.(Initializer); ok {
+			logrus.WithField("bridge", b.Opts().Name).Info("Initialising bridge")
+			if err := i.Init(ctx); err != nil {
+				closeBridges(inited)
+				return err
+			}
+			inited = append(inited, b)
+		}
+	}
+	return nil
+}
+
+// closeBridges calls Close on every bridge that implements io.Closer,
+// returning the first error given
+func closeBridges(bridges []Bridge) error {
+	var err error
+	for _, b := range bridges {
+		if c, ok := b.(io.Closer); ok {
+			logrus.WithField("bridge", b.Opts().Name).Info("Closing bridge")
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// closeBridges calls Close once on every bridge of the server that
+// implements io.Closer, returning the first error given
+func (s *Server) closeBridges() error {
+	var err error
+	s.closeOnce.Do(func() {
+		bridges := make([]Bridge, 0, len(s.pathMap))
+		for _, b := range s.pathMap {
+			bridges = append(bridges, b)
+		}
+		err = closeBridges(bridges)
+	})
+	return err
+}
@@ -0,0 +1,153 @@
+package bridges
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type Lifecycle struct {
+	initErr error
+	inited  bool
+	closed  bool
+	release chan struct{}
+}
+
+func (l *Lifecycle) Init(ctx context.Context) error {
+	l.inited = true
+	return l.initErr
+}
+
+func (l *Lifecycle) Close() error {
+	l.closed = true
+	return nil
+}
+
+func (l *Lifecycle) Run(h *Helper) (interface{}, error) {
+	<-l.release
+	return map[string]string{"key": "hello world"}, nil
+}
+
+func (l *Lifecycle) Opts() *Opts {
+	return &Opts{Name: "Lifecycle"}
+}
+
+func TestServer_Run_GracefulShutdown(t *testing.T) {
+	b := &Lifecycle{release: make(chan struct{})}
+	s := NewServer(b)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ec := make(chan error, 1)
+	go func() {
+		ec <- s.serve(ctx, ln, s.Mux())
+	}()
+
+	rc := make(chan int, 1)
+	go func() {
+		pb, _ := json.Marshal(map[string]interface{}{"id": "1234"})
+		resp, err := http.Post("http://"+ln.Addr().String(), "application/json", bytes.NewReader(pb))
+		if err != nil {
+			rc <- 0
+			return
+		}
+		resp.Body.Close()
+		rc <- resp.StatusCode
+	}()
+
+	// Give the request time to be in-flight before shutting down
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	close(b.release)
+
+	assert.Equal(t, http.StatusOK, <-rc)
+	assert.Nil(t, <-ec)
+	assert.True(t, b.inited)
+	assert.True(t, b.closed)
+}
+
+func TestServer_Run_InitError(t *testing.T) {
+	b := &Lifecycle{initErr: errors.New("init error")}
+	s := NewServer(b)
+	s.Addr = "127.0.0.1:0"
+
+	err := s.Run(context.Background())
+	assert.EqualError(t, err, "init error")
+	assert.False(t, b.closed)
+}
+
+type FailingInit struct {
+	Lifecycle
+}
+
+func (f *FailingInit) Opts() *Opts {
+	return &Opts{Name: "FailingInit", Path: "/fail"}
+}
+
+func TestServer_Run_InitErrorClosesInitialised(t *testing.T) {
+	b := &Lifecycle{}
+	f := &FailingInit{Lifecycle{initErr: errors.New("init error")}}
+	s := NewServer(b, f)
+	s.Addr = "127.0.0.1:0"
+
+	// Retry until the working bridge is initialised before the failing one
+	for i := 0; i < 100 && !b.inited; i++ {
+		b.inited, f.inited = false, false
+		assert.EqualError(t, s.Run(context.Background()), "init error")
+	}
+	assert.True(t, b.inited)
+	assert.True(t, b.closed)
+	assert.False(t, f.closed)
+}
+
+func TestServer_Shutdown_Deadline(t *testing.T) {
+	b := &Lifecycle{release: make(chan struct{})}
+	defer close(b.release)
+	s := NewServer(b)
+
+	s.pending.Add(1)
+	defer s.pending.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, s.Shutdown(ctx))
+	assert.True(t, b.closed)
+}
+
+func TestServer_Shutdown_DrainDelay(t *testing.T) {
+	s := NewServer(&Lifecycle{})
+	s.DrainDelay = 300 * time.Millisecond
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ec := make(chan error, 1)
+	go func() {
+		ec <- s.serve(ctx, ln, s.Mux())
+	}()
+	url := "http://" + ln.Addr().String() + ReadyPath
+
+	resp, err := http.Get(url)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	resp, err = http.Get(url)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	assert.Nil(t, <-ec)
+	_, err = http.Get(url)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,76 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/tidwall/gjson"
+)
+
+// Precedence decides which value is kept when the bridge output and
+// the request `data` have the same key
+type Precedence int
+
+const (
+	// PrecedenceResult keeps the values returned by the bridge
+	PrecedenceResult Precedence = iota
+	// PrecedenceRequest keeps the values given in the request `data`
+	PrecedenceRequest
+)
+
+// mergeData deep merges the bridge output into the request data, with nested
+// objects being merged and any other values replaced depending on the precedence.
+// Output that isn't an object is merged under the `result` key.
+func mergeData(data, out *JSON, p Precedence) (*JSON, error) {
+	dst := make(map[string]interface{})
+	if data != nil && data.IsObject() {
+		if err := decodeNumbers(data.Raw, &dst); err != nil {
+			return nil, err
+		}
+	}
+
+	src := make(map[string]interface{})
+	if out.IsObject() {
+		if err := decodeNumbers(out.Raw, &src); err != nil {
+			return nil, err
+		}
+	} else if out.Type == gjson.Number || out.IsArray() {
+		var v interface{}
+		if err := decodeNumbers(out.Raw, &v); err != nil {
+			return nil, err
+		}
+		src["result"] = v
+	} else if out.Exists() {
+		// The Raw of a string with escapes is truncated by gjson
+		src["result"] = out.Value()
+	}
+
+	deepMerge(dst, src, p == PrecedenceResult)
+	return ParseInterface(dst)
+}
+
+// deepMerge merges the src map into dst, replacing existing
+// values that aren't both objects if overwrite is set
+func deepMerge(dst, src map[string]interface{}, overwrite bool) {
+	for k, sv := range src {
+		dv, ok := dst[k]
+		if !ok {
+			dst[k] = sv
+			continue
+		}
+		dm, dok := dv.(map[string]interface{})
+		sm, sok := sv.(map[string]interface{})
+		if dok && sok {
+			deepMerge(dm, sm, overwrite)
+		} else if overwrite {
+			dst[k] = sv
+		}
+	}
+}
+
+// decodeNumbers decodes the JSON keeping numbers as their raw text,
+// so large values don't lose precision
+func decodeNumbers(raw string, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	d.UseNumber()
+	return d.Decode(v)
+}
@@ -0,0 +1,85 @@
+package bridges
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestMergeData(t *testing.T) {
+	data, err := Parse([]byte(`{"address":"0x01","amount":1000000000000000000000001,"opts":{"a":1,"b":2}}`))
+	assert.Nil(t, err)
+	out, err := Parse([]byte(`{"amount":2,"opts":{"b":3,"c":4},"extra":[1,2]}`))
+	assert.Nil(t, err)
+
+	merged, err := mergeData(data, out, PrecedenceResult)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"address": "0x01",
+		"amount": 2,
+		"opts": {"a": 1, "b": 3, "c": 4},
+		"extra": [1, 2]
+	}`, merged.Raw)
+
+	merged, err = mergeData(data, out, PrecedenceRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, "1000000000000000000000001", merged.Get("amount").Raw)
+	assert.Equal(t, int64(2), merged.Get("opts.b").Int())
+	assert.Equal(t, int64(4), merged.Get("opts.c").Int())
+
+	scalar, err := ParseInterface("1.5")
+	assert.Nil(t, err)
+	merged, err = mergeData(nil, scalar, PrecedenceResult)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"result":"1.5"}`, merged.Raw)
+
+	scalar, err = ParseInterface("say \"hi\"\nthere")
+	assert.Nil(t, err)
+	merged, err = mergeData(data, scalar, PrecedenceResult)
+	assert.Nil(t, err)
+	assert.Equal(t, "say \"hi\"\nthere", merged.Get("result").String())
+	assert.Equal(t, "0x01", merged.Get("address").String())
+
+	scalar, err = ParseInterface(1e21)
+	assert.Nil(t, err)
+	merged, err = mergeData(nil, scalar, PrecedenceResult)
+	assert.Nil(t, err)
+	assert.Equal(t, "1e+21", merged.Get("result").Raw)
+}
+
+type Merge struct {
+	obj        interface{}
+	precedence Precedence
+}
+
+func (m *Merge) Run(h *Helper) (interface{}, error) {
+	return m.obj, nil
+}
+
+func (m *Merge) Opts() *Opts {
+	return &Opts{
+		Name:            "Merge",
+		MergeData:       true,
+		MergePrecedence: m.precedence,
+	}
+}
+
+func TestServer_Mux_MergeData(t *testing.T) {
+	mux := NewServer(&Merge{obj: map[string]interface{}{"price": 100, "symbol": "BTC"}}).Mux()
+	in := map[string]interface{}{
+		"id":   "1234",
+		"data": map[string]interface{}{"functionSelector": "0xab", "symbol": "ETH"},
+	}
+
+	code, json := postRun(t, mux, in)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "0xab", json.Get("data.functionSelector").String())
+	assert.Equal(t, int64(100), json.Get("data.price").Int())
+	assert.Equal(t, "BTC", json.Get("data.symbol").String())
+
+	mux = NewServer(&Merge{obj: 100, precedence: PrecedenceRequest}).Mux()
+	code, json = postRun(t, mux, in)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ETH", json.Get("data.symbol").String())
+	assert.Equal(t, int64(100), json.Get("data.result").Int())
+}
@@ -0,0 +1,156 @@
+package bridges
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MetricsPath is the path the Prometheus metrics are served on
+const MetricsPath = "/metrics"
+
+// metrics holds the Prometheus collectors for a server, registered to
+// its own registry so multiple servers can run in the same process
+type metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	inFlight         *prometheus.GaugeVec
+	panics           *prometheus.CounterVec
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	breakerState     *prometheus.GaugeVec
+	cache            *prometheus.CounterVec
+	deduplicated     *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bridges_requests_total",
+			Help: "Total number of bridge requests by bridge, path and result status.",
+		}, []string{"bridge", "path", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "bridges_request_duration_seconds",
+			Help:    "Latency of bridge requests by bridge and path.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"bridge", "path"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bridges_requests_in_flight",
+			Help: "Number of bridge requests currently being served by bridge and path.",
+		}, []string{"bridge", "path"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bridges_panics_total",
+			Help: "Total number of panics recovered from by bridge.",
+		}, []string{"bridge"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bridges_upstream_requests_total",
+			Help: "Total number of upstream http calls made through the helper by host and status code.",
+		}, []string{"host", "code"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "bridges_upstream_request_duration_seconds",
+			Help:    "Latency of upstream http calls made through the helper by host and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host", "code"}),
+		breakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "bridges_circuit_breaker_state",
+			Help: "State of the circuit breaker by upstream host, where 0 is closed, 1 is half-open and 2 is open.",
+		}, []string{"host"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bridges_upstream_cache_total",
+			Help: "Total number of cache lookups for upstream calls by host and result, being hit, miss or stale.",
+		}, []string{"host", "result"}),
+		deduplicated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bridges_upstream_deduplicated_total",
+			Help: "Total number of upstream calls saved by sharing the response of an identical in-flight call by host.",
+		}, []string{"host"}),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.inFlight,
+		m.panics,
+		m.upstreamRequests,
+		m.upstreamDuration,
+		m.breakerState,
+		m.cache,
+		m.deduplicated,
+	)
+	return m
+}
+
+// Registry returns the Prometheus registry the server's metrics are
+// registered to, allowing bridges to register their own collectors
+func (s *Server) Registry() *prometheus.Registry {
+	return s.metrics.registry
+}
+
+// MetricsHandler returns the http.Handler serving the server's metrics
+// in the Prometheus exposition format
+func (s *Server) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(s.metrics.registry, promhttp.HandlerOpts{})
+}
+
+// startRequest marks a request as in-flight, returning the func
+// to call once it has been served with the result status
+func (m *metrics) startRequest(bridge, path string) func(status string) {
+	if m == nil {
+		return func(string) {}
+	}
+	start := time.Now()
+	g := m.inFlight.WithLabelValues(bridge, path)
+	g.Inc()
+	return func(status string) {
+		g.Dec()
+		m.requests.WithLabelValues(bridge, path, status).Inc()
+		m.requestDuration.WithLabelValues(bridge, path).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *metrics) observePanic(bridge string) {
+	if m == nil {
+		return
+	}
+	m.panics.WithLabelValues(bridge).Inc()
+}
+
+// observeUpstream records an upstream http call, where a zero code
+// means the call failed without a response
+func (m *metrics) observeUpstream(host string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	c := "error"
+	if code != 0 {
+		c = strconv.Itoa(code)
+	}
+	m.upstreamRequests.WithLabelValues(host, c).Inc()
+	m.upstreamDuration.WithLabelValues(host, c).Observe(d.Seconds())
+}
+
+func (m *metrics) observeBreakerState(host string, state breakerState) {
+	if m == nil {
+		return
+	}
+	m.breakerState.WithLabelValues(host).Set(float64(state))
+}
+
+func (m *metrics) observeCache(host, result string) {
+	if m == nil {
+		return
+	}
+	m.cache.WithLabelValues(host, result).Inc()
+}
+
+func (m *metrics) observeDedupe(host string) {
+	if m == nil {
+		return
+	}
+	m.deduplicated.WithLabelValues(host).Inc()
+}
@@ -0,0 +1,69 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type Upstream struct {
+	url string
+}
+
+func (u *Upstream) Run(h *Helper) (interface{}, error) {
+	r := make(map[string]interface{})
+	err := h.HTTPCall(http.MethodGet, u.url, &r)
+	return r, err
+}
+
+func (u *Upstream) Opts() *Opts {
+	return &Opts{
+		Name:   "Upstream",
+		Path:   "/upstream",
+		Lambda: true,
+	}
+}
+
+func TestServer_Mux_Metrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"price":1}`)
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL)
+	assert.Nil(t, err)
+
+	mux := NewServer(&Upstream{url: upstream.URL}, &Panic{}).Mux()
+
+	for _, p := range []string{"/upstream", "/", "/invalid"} {
+		pb, err := json.Marshal(map[string]interface{}{"id": "1234"})
+		assert.Nil(t, err)
+		req, err := http.NewRequest(http.MethodPost, p, bytes.NewReader(pb))
+		assert.Nil(t, err)
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, MetricsPath, nil)
+	assert.Nil(t, err)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body, err := ioutil.ReadAll(rr.Body)
+	assert.Nil(t, err)
+	m := string(body)
+
+	assert.Contains(t, m, `bridges_requests_total{bridge="Upstream",path="/upstream",status="completed"} 1`)
+	assert.Contains(t, m, `bridges_requests_total{bridge="Panic",path="/",status="errored"} 1`)
+	assert.Contains(t, m, `bridges_requests_total{bridge="",path="unknown",status="errored"} 1`)
+	assert.Contains(t, m, `bridges_request_duration_seconds_count{bridge="Upstream",path="/upstream"} 1`)
+	assert.Contains(t, m, `bridges_requests_in_flight{bridge="Upstream",path="/upstream"} 0`)
+	assert.Contains(t, m, `bridges_panics_total{bridge="Panic"} 1`)
+	assert.Contains(t, m, fmt.Sprintf(`bridges_upstream_requests_total{code="200",host="%s"} 1`, u.Host))
+	assert.Contains(t, m, fmt.Sprintf(`bridges_upstream_request_duration_seconds_count{code="200",host="%s"} 1`, u.Host))
+}
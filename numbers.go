@@ -0,0 +1,54 @@
+package bridges
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+)
+
+// BigInt returns the arbitrary-precision integer value of the JSON, parsed from
+// the raw number text or a decimal or `0x` prefixed hex string. False is returned
+// if it isn't an integer.
+func (j *JSON) BigInt() (*big.Int, bool) {
+	if j == nil || !j.Exists() {
+		return nil, false
+	}
+	return parseBigInt(rawNumber(j.Result))
+}
+
+// Decimal returns the exact decimal value of the JSON, parsed from the raw number
+// text or a numeric string, unlike Float which loses precision on values such as
+// 18 decimal token amounts. False is returned if it isn't a number.
+func (j *JSON) Decimal() (*big.Rat, bool) {
+	if j == nil || !j.Exists() {
+		return nil, false
+	}
+	return parseDecimal(rawNumber(j.Result))
+}
+
+// GetBigInt returns the arbitrary-precision integer value at the path
+func (j *JSON) GetBigInt(path string) (*big.Int, bool) {
+	if j == nil {
+		return nil, false
+	}
+	return (&JSON{j.Get(path)}).BigInt()
+}
+
+// GetDecimal returns the exact decimal value at the path
+func (j *JSON) GetDecimal(path string) (*big.Rat, bool) {
+	if j == nil {
+		return nil, false
+	}
+	return (&JSON{j.Get(path)}).Decimal()
+}
+
+// unmarshal decodes the JSON into the object, keeping numbers decoded into an
+// interface as json.Number if the helper is set to use precise numbers
+func (h *Helper) unmarshal(b []byte, obj interface{}) error {
+	if !h.preciseNumbers {
+		return json.Unmarshal(b, obj)
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(obj)
+}
@@ -0,0 +1,74 @@
+package bridges
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"testing"
+)
+
+func TestJSON_BigNumbers(t *testing.T) {
+	j, err := Parse([]byte(`{
+		"amount": 1000000000000000000000001,
+		"price": "1234.000000000000000001",
+		"hex": "0xde0b6b3a7640000",
+		"text": "abc"
+	}`))
+	assert.Nil(t, err)
+
+	n, ok := j.GetBigInt("amount")
+	assert.True(t, ok)
+	assert.Equal(t, "1000000000000000000000001", n.String())
+	n, ok = j.GetBigInt("hex")
+	assert.True(t, ok)
+	assert.Equal(t, big.NewInt(1000000000000000000), n)
+	_, ok = j.GetBigInt("price")
+	assert.False(t, ok)
+
+	d, ok := j.GetDecimal("price")
+	assert.True(t, ok)
+	assert.Equal(t, "1234.000000000000000001", d.FloatString(18))
+	d, ok = j.GetDecimal("amount")
+	assert.True(t, ok)
+	assert.True(t, d.IsInt())
+	_, ok = j.GetDecimal("text")
+	assert.False(t, ok)
+	_, ok = j.GetDecimal("missing")
+	assert.False(t, ok)
+
+	var nilJSON *JSON
+	_, ok = nilJSON.Decimal()
+	assert.False(t, ok)
+}
+
+func TestHelper_DecimalParam(t *testing.T) {
+	data, err := Parse([]byte(`{"price":"0.1","times":1e18,"fraction":"1/3"}`))
+	assert.Nil(t, err)
+	h := NewHelper(data)
+
+	p := h.GetDecimalParam("price")
+	times := h.GetDecimalParam("times")
+	assert.Equal(t, "100000000000000000", new(big.Rat).Mul(p, times).FloatString(0))
+	assert.Nil(t, h.GetDecimalParam("fraction"))
+	assert.Equal(t, big.NewRat(1, 2), h.GetDecimalParamOrDefault("missing", big.NewRat(1, 2)))
+
+	_, err = h.RequireDecimalParam("fraction")
+	assert.EqualError(t, err, `Invalid parameter "fraction": not a number`)
+}
+
+func TestServer_Mux_PreciseNumbers(t *testing.T) {
+	for _, precise := range []bool{false, true} {
+		s := NewServer(&Price{url: "http://upstream/price"})
+		s.HTTPClient = &http.Client{Transport: stubTransport(`{"price":1000000000000000000000001.5}`)}
+		s.PreciseNumbers = precise
+		mux := s.Mux()
+
+		code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+		assert.Equal(t, http.StatusOK, code)
+		if precise {
+			assert.Equal(t, "1000000000000000000000001.5", json.Get("data").Raw)
+		} else {
+			assert.NotEqual(t, "1000000000000000000000001.5", json.Get("data").Raw)
+		}
+	}
+}
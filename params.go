@@ -0,0 +1,339 @@
+package bridges
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParamError is returned when a parameter in the request `data` is missing
+// or invalid, with the run being errored as a bad request
+type ParamError struct {
+	Key string
+	Err error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("Invalid parameter %q: %s", e.Key, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode is the status code given for the errored result
+func (e *ParamError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// ErrMissingParam is the ParamError cause when a required parameter isn't given
+var ErrMissingParam = errors.New("missing required parameter")
+
+// param returns the value of a key in the request data, and whether it was given
+func (h *Helper) param(key string) (gjson.Result, bool) {
+	if h.Data == nil {
+		return gjson.Result{}, false
+	}
+	r := h.Data.Get(key)
+	return r, r.Exists() && r.Type != gjson.Null
+}
+
+// GetParamOrDefault gets the string value of a key in the `data` JSON object,
+// returning the default if it's not given
+func (h *Helper) GetParamOrDefault(key, def string) string {
+	if r, ok := h.param(key); ok {
+		return r.String()
+	}
+	return def
+}
+
+// RequireParam gets the string value of a key in the `data` JSON object,
+// returning a ParamError if it's not given
+func (h *Helper) RequireParam(key string) (string, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return "", &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	return r.String(), nil
+}
+
+// GetIntParamOrDefault gets the int64 value of a key in the `data` JSON object,
+// returning the default if it's not given or invalid
+func (h *Helper) GetIntParamOrDefault(key string, def int64) int64 {
+	if v, err := h.RequireIntParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireIntParam gets the int64 value of a key in the `data` JSON object,
+// returning a ParamError if it's not given or not an integer
+func (h *Helper) RequireIntParam(key string) (int64, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return 0, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(rawNumber(r)), 10, 64)
+	if err != nil {
+		return 0, &ParamError{Key: key, Err: errors.New("not an integer")}
+	}
+	return v, nil
+}
+
+// GetFloatParam gets the float64 value of a key in the `data` JSON object
+func (h *Helper) GetFloatParam(key string) float64 {
+	return h.GetFloatParamOrDefault(key, 0)
+}
+
+// GetFloatParamOrDefault gets the float64 value of a key in the `data` JSON
+// object, returning the default if it's not given or invalid
+func (h *Helper) GetFloatParamOrDefault(key string, def float64) float64 {
+	if v, err := h.RequireFloatParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireFloatParam gets the float64 value of a key in the `data` JSON object,
+// returning a ParamError if it's not given or not a number
+func (h *Helper) RequireFloatParam(key string) (float64, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return 0, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(rawNumber(r)), 64)
+	if err != nil {
+		return 0, &ParamError{Key: key, Err: errors.New("not a number")}
+	}
+	return v, nil
+}
+
+// GetBoolParam gets the bool value of a key in the `data` JSON object
+func (h *Helper) GetBoolParam(key string) bool {
+	return h.GetBoolParamOrDefault(key, false)
+}
+
+// GetBoolParamOrDefault gets the bool value of a key in the `data` JSON
+// object, returning the default if it's not given or invalid
+func (h *Helper) GetBoolParamOrDefault(key string, def bool) bool {
+	if v, err := h.RequireBoolParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireBoolParam gets the bool value of a key in the `data` JSON object,
+// accepting JSON booleans or strings such as "true" and "1", returning a
+// ParamError if it's not given or not a boolean
+func (h *Helper) RequireBoolParam(key string) (bool, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return false, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	switch r.Type {
+	case gjson.True:
+		return true, nil
+	case gjson.False:
+		return false, nil
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(r.String()))
+	if err != nil {
+		return false, &ParamError{Key: key, Err: errors.New("not a boolean")}
+	}
+	return v, nil
+}
+
+// GetBigIntParam gets the arbitrary-precision integer value of a key in the
+// `data` JSON object, returning nil if it's not given or invalid
+func (h *Helper) GetBigIntParam(key string) *big.Int {
+	return h.GetBigIntParamOrDefault(key, nil)
+}
+
+// GetBigIntParamOrDefault gets the arbitrary-precision integer value of a key
+// in the `data` JSON object, returning the default if it's not given or invalid
+func (h *Helper) GetBigIntParamOrDefault(key string, def *big.Int) *big.Int {
+	if v, err := h.RequireBigIntParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireBigIntParam gets the arbitrary-precision integer value of a key in the
+// `data` JSON object, parsed from the raw number text or a decimal or `0x`
+// prefixed hex string, returning a ParamError if it's not given or invalid
+func (h *Helper) RequireBigIntParam(key string) (*big.Int, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return nil, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	v, ok := parseBigInt(rawNumber(r))
+	if !ok {
+		return nil, &ParamError{Key: key, Err: errors.New("not an integer")}
+	}
+	return v, nil
+}
+
+// GetDecimalParam gets the exact decimal value of a key in the `data` JSON
+// object, returning nil if it's not given or invalid
+func (h *Helper) GetDecimalParam(key string) *big.Rat {
+	return h.GetDecimalParamOrDefault(key, nil)
+}
+
+// GetDecimalParamOrDefault gets the exact decimal value of a key in the `data`
+// JSON object, returning the default if it's not given or invalid
+func (h *Helper) GetDecimalParamOrDefault(key string, def *big.Rat) *big.Rat {
+	if v, err := h.RequireDecimalParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireDecimalParam gets the exact decimal value of a key in the `data` JSON
+// object, parsed from the raw number text or a numeric string without the loss
+// of precision of GetFloatParam, returning a ParamError if it's not given or invalid
+func (h *Helper) RequireDecimalParam(key string) (*big.Rat, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return nil, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	v, ok := parseDecimal(rawNumber(r))
+	if !ok {
+		return nil, &ParamError{Key: key, Err: errors.New("not a number")}
+	}
+	return v, nil
+}
+
+// GetStringSliceParam gets the string slice value of a key in the `data`
+// JSON object, returning nil if it's not given
+func (h *Helper) GetStringSliceParam(key string) []string {
+	return h.GetStringSliceParamOrDefault(key, nil)
+}
+
+// GetStringSliceParamOrDefault gets the string slice value of a key in the
+// `data` JSON object, returning the default if it's not given
+func (h *Helper) GetStringSliceParamOrDefault(key string, def []string) []string {
+	if v, err := h.RequireStringSliceParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireStringSliceParam gets the string slice value of a key in the `data`
+// JSON object, where a single value is given as a slice of one, returning a
+// ParamError if it's not given
+func (h *Helper) RequireStringSliceParam(key string) ([]string, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return nil, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	if !r.IsArray() {
+		return []string{r.String()}, nil
+	}
+	var v []string
+	for _, e := range r.Array() {
+		v = append(v, e.String())
+	}
+	return v, nil
+}
+
+// GetDurationParam gets the duration value of a key in the `data` JSON object
+func (h *Helper) GetDurationParam(key string) time.Duration {
+	return h.GetDurationParamOrDefault(key, 0)
+}
+
+// GetDurationParamOrDefault gets the duration value of a key in the `data`
+// JSON object, returning the default if it's not given or invalid
+func (h *Helper) GetDurationParamOrDefault(key string, def time.Duration) time.Duration {
+	if v, err := h.RequireDurationParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireDurationParam gets the duration value of a key in the `data` JSON
+// object, given either as a string such as "1m30s" or a number of seconds,
+// returning a ParamError if it's not given or invalid
+func (h *Helper) RequireDurationParam(key string) (time.Duration, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return 0, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	if r.Type == gjson.Number {
+		return time.Duration(r.Float() * float64(time.Second)), nil
+	}
+	v, err := time.ParseDuration(strings.TrimSpace(r.String()))
+	if err != nil {
+		return 0, &ParamError{Key: key, Err: errors.New("not a duration")}
+	}
+	return v, nil
+}
+
+// GetTimeParam gets the time value of a key in the `data` JSON object
+func (h *Helper) GetTimeParam(key string) time.Time {
+	return h.GetTimeParamOrDefault(key, time.Time{})
+}
+
+// GetTimeParamOrDefault gets the time value of a key in the `data` JSON
+// object, returning the default if it's not given or invalid
+func (h *Helper) GetTimeParamOrDefault(key string, def time.Time) time.Time {
+	if v, err := h.RequireTimeParam(key); err == nil {
+		return v
+	}
+	return def
+}
+
+// RequireTimeParam gets the time value of a key in the `data` JSON object,
+// given either as an RFC 3339 string or a unix timestamp in seconds,
+// returning a ParamError if it's not given or invalid
+func (h *Helper) RequireTimeParam(key string) (time.Time, error) {
+	r, ok := h.param(key)
+	if !ok {
+		return time.Time{}, &ParamError{Key: key, Err: ErrMissingParam}
+	}
+	if r.Type == gjson.Number {
+		return time.Unix(r.Int(), 0).UTC(), nil
+	}
+	v, err := time.Parse(time.RFC3339, strings.TrimSpace(r.String()))
+	if err != nil {
+		return time.Time{}, &ParamError{Key: key, Err: errors.New("not an RFC 3339 time")}
+	}
+	return v, nil
+}
+
+// rawNumber returns the raw text of a JSON number to avoid any loss of
+// precision, or the value of a JSON string
+func rawNumber(r gjson.Result) string {
+	if r.Type == gjson.Number {
+		return r.Raw
+	}
+	return r.String()
+}
+
+// parseBigInt parses a decimal or `0x` prefixed hex integer
+func parseBigInt(s string) (*big.Int, bool) {
+	s = strings.TrimSpace(s)
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	} else if strings.HasPrefix(s, "-0x") || strings.HasPrefix(s, "-0X") {
+		s, base = "-"+s[3:], 16
+	}
+	return new(big.Int).SetString(s, base)
+}
+
+// parseDecimal parses a decimal number, which can be in exponent form,
+// or a `0x` prefixed hex integer
+func parseDecimal(s string) (*big.Rat, bool) {
+	if n, ok := parseBigInt(s); ok {
+		return new(big.Rat).SetInt(n), true
+	}
+	if strings.Contains(s, "/") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strings.TrimSpace(s))
+}
@@ -0,0 +1,132 @@
+package bridges
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newParamsHelper(t *testing.T) *Helper {
+	data, err := Parse([]byte(`{
+		"string": "hello",
+		"int": 42,
+		"intString": "42",
+		"float": 1.5,
+		"bool": true,
+		"boolString": "false",
+		"bigInt": 1000000000000000000000000,
+		"bigIntString": "-1000000000000000000000001",
+		"bigIntHex": "0xde0b6b3a7640000",
+		"slice": ["a", "b"],
+		"duration": "1m30s",
+		"durationSeconds": 90,
+		"time": "2019-10-01T12:00:00Z",
+		"timeUnix": 1569931200,
+		"null": null
+	}`))
+	assert.Nil(t, err)
+	return NewHelper(data)
+}
+
+func TestHelper_TypedParams(t *testing.T) {
+	h := newParamsHelper(t)
+
+	assert.Equal(t, "hello", h.GetParam("string"))
+	assert.Equal(t, "", h.GetParam("missing"))
+	assert.Equal(t, "default", h.GetParamOrDefault("null", "default"))
+
+	assert.Equal(t, int64(42), h.GetIntParam("int"))
+	assert.Equal(t, int64(42), h.GetIntParamOrDefault("intString", 1))
+	assert.Equal(t, int64(1), h.GetIntParamOrDefault("string", 1))
+
+	assert.Equal(t, 1.5, h.GetFloatParam("float"))
+	assert.Equal(t, float64(42), h.GetFloatParam("intString"))
+	assert.Equal(t, 2.5, h.GetFloatParamOrDefault("missing", 2.5))
+
+	assert.True(t, h.GetBoolParam("bool"))
+	assert.False(t, h.GetBoolParamOrDefault("boolString", true))
+	assert.True(t, h.GetBoolParamOrDefault("string", true))
+
+	e, _ := new(big.Int).SetString("1000000000000000000000000", 10)
+	assert.Equal(t, e, h.GetBigIntParam("bigInt"))
+	e, _ = new(big.Int).SetString("-1000000000000000000000001", 10)
+	assert.Equal(t, e, h.GetBigIntParam("bigIntString"))
+	assert.Equal(t, big.NewInt(1000000000000000000), h.GetBigIntParam("bigIntHex"))
+	assert.Nil(t, h.GetBigIntParam("float"))
+	assert.Equal(t, big.NewInt(1), h.GetBigIntParamOrDefault("missing", big.NewInt(1)))
+
+	assert.Equal(t, []string{"a", "b"}, h.GetStringSliceParam("slice"))
+	assert.Equal(t, []string{"hello"}, h.GetStringSliceParam("string"))
+	assert.Nil(t, h.GetStringSliceParam("missing"))
+
+	assert.Equal(t, 90*time.Second, h.GetDurationParam("duration"))
+	assert.Equal(t, 90*time.Second, h.GetDurationParam("durationSeconds"))
+	assert.Equal(t, time.Second, h.GetDurationParamOrDefault("string", time.Second))
+
+	e2 := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, e2, h.GetTimeParam("time"))
+	assert.Equal(t, e2, h.GetTimeParam("timeUnix"))
+	assert.True(t, h.GetTimeParam("missing").IsZero())
+}
+
+func TestHelper_RequireParams(t *testing.T) {
+	h := newParamsHelper(t)
+
+	_, err := h.RequireParam("missing")
+	assert.EqualError(t, err, `Invalid parameter "missing": missing required parameter`)
+	assert.True(t, errors.Is(err, ErrMissingParam))
+	_, err = h.RequireParam("null")
+	assert.True(t, errors.Is(err, ErrMissingParam))
+
+	_, err = h.RequireIntParam("float")
+	assert.EqualError(t, err, `Invalid parameter "float": not an integer`)
+	_, err = h.RequireFloatParam("string")
+	assert.EqualError(t, err, `Invalid parameter "string": not a number`)
+	_, err = h.RequireBoolParam("string")
+	assert.EqualError(t, err, `Invalid parameter "string": not a boolean`)
+	_, err = h.RequireBigIntParam("string")
+	assert.EqualError(t, err, `Invalid parameter "string": not an integer`)
+	_, err = h.RequireDurationParam("string")
+	assert.EqualError(t, err, `Invalid parameter "string": not a duration`)
+	_, err = h.RequireTimeParam("string")
+	assert.EqualError(t, err, `Invalid parameter "string": not an RFC 3339 time`)
+
+	v, err := h.RequireStringSliceParam("slice")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, v)
+}
+
+func TestHelper_ParamsNilData(t *testing.T) {
+	h := NewHelper(nil)
+	assert.Equal(t, "", h.GetParam("key"))
+	assert.Equal(t, int64(0), h.GetIntParam("key"))
+	_, err := h.RequireParam("key")
+	assert.True(t, errors.Is(err, ErrMissingParam))
+}
+
+type RequireParam struct{}
+
+func (rp *RequireParam) Run(h *Helper) (interface{}, error) {
+	s, err := h.RequireParam("symbol")
+	return map[string]string{"symbol": s}, err
+}
+
+func (rp *RequireParam) Opts() *Opts {
+	return &Opts{Name: "RequireParam"}
+}
+
+func TestServer_Mux_ParamError(t *testing.T) {
+	mux := NewServer(&RequireParam{}).Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "errored", json.Get("status").String())
+	assert.Equal(t, `Invalid parameter "symbol": missing required parameter`, json.Get("error").String())
+
+	code, json = postRun(t, mux, map[string]interface{}{"id": "1234", "data": map[string]string{"symbol": "ETH"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ETH", json.Get("data.symbol").String())
+}
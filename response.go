@@ -0,0 +1,141 @@
+package bridges
+
+import (
+	"encoding/json"
+	"github.com/tidwall/gjson"
+	"net/http"
+)
+
+// ResponseSchema is the format of the response given to the node
+type ResponseSchema string
+
+const (
+	// SchemaLegacy is the `jobRunId/status/error/pending/data` format
+	// expected by older Chainlink nodes, and the default
+	SchemaLegacy ResponseSchema = "legacy"
+	// SchemaV2 is the external adapter v2 format expected by newer
+	// Chainlink nodes, given as an AdapterResponse
+	SchemaV2 ResponseSchema = "v2"
+	// SchemaAuto detects the format from the request, using the legacy format
+	// if the request has a `jobRunId` or `responseURL` and v2 otherwise
+	SchemaAuto ResponseSchema = "auto"
+)
+
+// AdapterResponse is the external adapter v2 response format
+type AdapterResponse struct {
+	JobRunID           string          `json:"jobRunID"`
+	Status             string          `json:"status"`
+	Data               *JSON           `json:"data,omitempty"`
+	Result             json.RawMessage `json:"result,omitempty"`
+	StatusCode         int             `json:"statusCode"`
+	ProviderStatusCode int             `json:"providerStatusCode,omitempty"`
+	Error              *AdapterError   `json:"error,omitempty"`
+}
+
+// AdapterError is the error object of an errored AdapterResponse
+type AdapterError struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// NewAdapterResponse returns the v2 format of the result responded with the
+// status code. If the bridge returned an object, it's given as `data` with
+// `result` taken from its `result` key. Anything else is given as `result`,
+// and as `result` within `data`.
+func NewAdapterResponse(rt *Result, code int) *AdapterResponse {
+	ar := &AdapterResponse{
+		JobRunID:           rt.JobRunID,
+		Status:             rt.Status,
+		StatusCode:         code,
+		ProviderStatusCode: rt.ProviderStatusCode,
+	}
+	if rt.Status == "errored" {
+		ar.Error = &AdapterError{
+			Name:    adapterErrorName(code, rt.ProviderStatusCode),
+			Message: rt.Error.String,
+		}
+		return ar
+	}
+	if rt.Data == nil || !rt.Data.Exists() {
+		return ar
+	}
+
+	if rt.Data.IsObject() {
+		ar.Data = rt.Data
+		if r := rt.Data.Get("result"); r.Exists() {
+			ar.Result = json.RawMessage(r.Raw)
+		}
+	} else if r, err := rawResult(rt.Data.Result); err == nil {
+		ar.Result = r
+		ar.Data, _ = ParseInterface(map[string]json.RawMessage{"result": r})
+	}
+	return ar
+}
+
+// rawResult returns the JSON of a result that isn't an object. Strings and
+// other scalars are marshalled from their value, as the Raw of a string
+// with escapes is truncated by gjson, while numbers and arrays are kept as
+// their Raw so large numbers don't lose precision.
+func rawResult(r gjson.Result) (json.RawMessage, error) {
+	if r.Type == gjson.Number || r.IsArray() {
+		return json.RawMessage(r.Raw), nil
+	}
+	return json.Marshal(r.Value())
+}
+
+// adapterErrorName returns the name of the error based on its status code,
+// following the names given by other external adapters
+func adapterErrorName(code, providerCode int) string {
+	switch {
+	case code == http.StatusGatewayTimeout:
+		return "AdapterTimeoutError"
+	case code >= 400 && code < 500:
+		return "AdapterInputError"
+	case providerCode >= 400:
+		return "AdapterDataProviderError"
+	}
+	return "AdapterError"
+}
+
+// responseSchema returns the response schema set for the bridge, falling back
+// to the server's schema. The bridge can be nil if the path isn't known.
+func (s *Server) responseSchema(b Bridge) ResponseSchema {
+	if b != nil {
+		if rs := s.opts(b).ResponseSchema; len(rs) > 0 {
+			return rs
+		}
+	}
+	if len(s.ResponseSchema) > 0 {
+		return s.ResponseSchema
+	}
+	return SchemaLegacy
+}
+
+// resolveSchema returns the schema to respond with, detecting it from the
+// request if it's SchemaAuto. It must be called before SetJobRunID.
+func resolveSchema(rs ResponseSchema, rt *Result) ResponseSchema {
+	if rs != SchemaAuto {
+		return rs
+	}
+	if len(rt.JobRunID) > 0 || len(rt.ResponseURL) > 0 {
+		return SchemaLegacy
+	}
+	return SchemaV2
+}
+
+// response returns the result in the format of the schema
+func (s *Server) response(rs ResponseSchema, rt *Result, code int) interface{} {
+	if rs == SchemaV2 {
+		return NewAdapterResponse(rt, code)
+	} else if s.PreciseNumbers && rt.Data != nil && rt.Data.Type == gjson.Number {
+		return &preciseResult{Result: rt, Data: json.RawMessage(rt.Data.Raw)}
+	}
+	return rt
+}
+
+// preciseResult is a result with its number data given as the raw JSON, so it's
+// given exactly as it was returned rather than formatted as a float
+type preciseResult struct {
+	*Result
+	Data json.RawMessage `json:"data"`
+}
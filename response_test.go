@@ -0,0 +1,134 @@
+package bridges
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type Price struct {
+	url    string
+	schema ResponseSchema
+}
+
+func (p *Price) Run(h *Helper) (interface{}, error) {
+	r := make(map[string]interface{})
+	err := h.HTTPCall(http.MethodGet, p.url, &r)
+	return r["price"], err
+}
+
+func (p *Price) Opts() *Opts {
+	return &Opts{
+		Name:           "Price",
+		Lambda:         true,
+		ResponseSchema: p.schema,
+	}
+}
+
+func newPriceUpstream(code int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		fmt.Fprint(w, `{"price":"1000000000000000000000"}`)
+	}))
+}
+
+func TestServer_Mux_ResponseSchemaV2(t *testing.T) {
+	upstream := newPriceUpstream(http.StatusOK)
+	defer upstream.Close()
+	mux := NewServer(&Price{url: upstream.URL, schema: SchemaV2}).Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1234", json.Get("jobRunID").String())
+	assert.Equal(t, "completed", json.Get("status").String())
+	assert.Equal(t, "1000000000000000000000", json.Get("result").String())
+	assert.Equal(t, "1000000000000000000000", json.Get("data.result").String())
+	assert.Equal(t, int64(200), json.Get("statusCode").Int())
+	assert.Equal(t, int64(200), json.Get("providerStatusCode").Int())
+	assert.False(t, json.Get("error").Exists())
+	assert.False(t, json.Get("jobRunId").Exists())
+}
+
+func TestServer_Mux_ResponseSchemaV2EscapedString(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"price":"say \"hi\"\nthere"}`)
+	}))
+	defer upstream.Close()
+	mux := NewServer(&Price{url: upstream.URL, schema: SchemaV2}).Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "completed", json.Get("status").String())
+	assert.Equal(t, "say \"hi\"\nthere", json.Get("result").String())
+	assert.Equal(t, "say \"hi\"\nthere", json.Get("data.result").String())
+}
+
+func TestServer_Mux_ResponseSchemaV2Errored(t *testing.T) {
+	upstream := newPriceUpstream(http.StatusServiceUnavailable)
+	defer upstream.Close()
+	s := NewServer(&Price{url: upstream.URL})
+	s.ResponseSchema = SchemaV2
+	mux := s.Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{"id": "1234"})
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "errored", json.Get("status").String())
+	assert.Equal(t, int64(500), json.Get("statusCode").Int())
+	assert.Equal(t, int64(503), json.Get("providerStatusCode").Int())
+	assert.Equal(t, "AdapterDataProviderError", json.Get("error.name").String())
+	assert.Equal(t, "Unexpected api status code: 503", json.Get("error.message").String())
+	assert.False(t, json.Get("data").Exists())
+}
+
+func TestServer_Mux_ResponseSchemaAuto(t *testing.T) {
+	upstream := newPriceUpstream(http.StatusOK)
+	defer upstream.Close()
+	s := NewServer(&Price{url: upstream.URL})
+	s.ResponseSchema = SchemaAuto
+	mux := s.Mux()
+
+	_, json := postRun(t, mux, map[string]interface{}{"id": "1234", "data": map[string]string{}})
+	assert.Equal(t, "1234", json.Get("jobRunID").String())
+	assert.Equal(t, "1000000000000000000000", json.Get("result").String())
+
+	_, json = postRun(t, mux, map[string]interface{}{"jobRunId": "1234"})
+	assert.Equal(t, "1234", json.Get("jobRunId").String())
+	assert.Equal(t, "1000000000000000000000", json.Get("data").String())
+	assert.False(t, json.Get("result").Exists())
+}
+
+func TestServer_Lambda_ResponseSchemaV2(t *testing.T) {
+	upstream := newPriceUpstream(http.StatusOK)
+	defer upstream.Close()
+	s := NewServer(&Price{url: upstream.URL, schema: SchemaV2})
+
+	obj, err := s.Lambda(&Result{ID: "1234"})
+	assert.Nil(t, err)
+	ar, ok := obj.(*AdapterResponse)
+	assert.True(t, ok)
+	assert.Equal(t, "1234", ar.JobRunID)
+	assert.Equal(t, `"1000000000000000000000"`, string(ar.Result))
+	assert.Equal(t, http.StatusOK, ar.StatusCode)
+}
+
+func TestNewAdapterResponse(t *testing.T) {
+	data, err := Parse([]byte(`{"result":1.5,"symbol":"ETH"}`))
+	assert.Nil(t, err)
+	rt := &Result{JobRunID: "1234", Data: data}
+	rt.SetCompleted()
+	ar := NewAdapterResponse(rt, http.StatusOK)
+	assert.Equal(t, "1.5", string(ar.Result))
+	assert.Equal(t, "ETH", ar.Data.Get("symbol").String())
+
+	rt.SetErrored(&ParamError{Key: "symbol", Err: ErrMissingParam})
+	ar = NewAdapterResponse(rt, http.StatusBadRequest)
+	assert.Equal(t, &AdapterError{
+		Name:    "AdapterInputError",
+		Message: `Invalid parameter "symbol": missing required parameter`,
+	}, ar.Error)
+
+	rt.SetErrored(fmt.Errorf("Bridge run timed out"))
+	assert.Equal(t, "AdapterTimeoutError", NewAdapterResponse(rt, http.StatusGatewayTimeout).Error.Name)
+}
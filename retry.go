@@ -0,0 +1,181 @@
+package bridges
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryPolicy is a sensible retry policy for flaky upstream APIs,
+// with any zero fields of a given RetryPolicy falling back to these values
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryableCodes: []int{
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// RetryPolicy sets how failed http calls are retried, using exponential
+// backoff with jitter between attempts
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made, including the first
+	MaxAttempts int `json:"maxAttempts"`
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration `json:"initialBackoff"`
+	// MaxBackoff caps the wait between attempts. If the upstream responds with
+	// a `Retry-After` longer than this, the call isn't retried.
+	MaxBackoff time.Duration `json:"maxBackoff"`
+	// Multiplier is the factor the backoff increases by after each attempt
+	Multiplier float64 `json:"multiplier"`
+	// Jitter is the fraction of the backoff that is randomly added or
+	// removed, where zero means no jitter
+	Jitter float64 `json:"jitter"`
+	// RetryableCodes are the response status codes that are retried
+	RetryableCodes []int `json:"retryableCodes"`
+	// RetryableError decides whether a failed call with no response is retried.
+	// By default any error is retried, including the http client timing out.
+	// Calls cancelled by the caller and open circuit breakers aren't retried.
+	RetryableError func(error) bool `json:"-"`
+}
+
+// withDefaults returns a copy of the policy with any zero fields set from
+// the DefaultRetryPolicy. A nil policy only makes a single attempt.
+func (rp *RetryPolicy) withDefaults() RetryPolicy {
+	if rp == nil {
+		p := DefaultRetryPolicy
+		p.MaxAttempts = 1
+		return p
+	}
+	p := *rp
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	if p.RetryableCodes == nil {
+		p.RetryableCodes = DefaultRetryPolicy.RetryableCodes
+	}
+	return p
+}
+
+// retryable returns whether the error of a failed attempt can be retried.
+// Whether the caller gave up on the call is decided by its request context,
+// as a timeout of the http client also gives context.DeadlineExceeded.
+func (rp RetryPolicy) retryable(err error) bool {
+	var sce *statusCodeError
+	if errors.As(err, &sce) {
+		for _, c := range rp.RetryableCodes {
+			if c == sce.Code {
+				return true
+			}
+		}
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	if rp.RetryableError != nil {
+		return rp.RetryableError(err)
+	}
+	return true
+}
+
+// backoff returns the wait before the next attempt, after the given
+// number of attempts have been made
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	b := float64(rp.InitialBackoff) * math.Pow(rp.Multiplier, float64(attempt-1))
+	if rp.Jitter > 0 {
+		b += b * rp.Jitter * (2*rand.Float64() - 1)
+	}
+	if b > float64(rp.MaxBackoff) {
+		b = float64(rp.MaxBackoff)
+	}
+	return time.Duration(b)
+}
+
+// doWithRetry performs the http call, retrying as per the retry policy
+// and failing fast if the host's circuit breaker is open
+func (h *Helper) doWithRetry(req *http.Request, opts CallOpts) (*upstreamResponse, error) {
+	rp := opts.Retry.withDefaults()
+	for attempt := 1; ; attempt++ {
+		resp, err := h.doWithBreaker(cloneRequest(req), opts, rp)
+		if err == nil || attempt >= rp.MaxAttempts || req.Context().Err() != nil || !rp.retryable(err) {
+			return resp, err
+		}
+
+		wait := rp.backoff(attempt)
+		if ra, ok := retryAfter(err); ok {
+			if ra > rp.MaxBackoff {
+				return resp, err
+			}
+			wait = ra
+		}
+		logrus.WithFields(logrus.Fields{
+			"host":    req.URL.Host,
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Warnf("Retrying failed api call: %v", err)
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return resp, err
+		}
+	}
+}
+
+// retryAfter parses the `Retry-After` header of an unexpected response,
+// given either in seconds or as a http date
+func retryAfter(err error) (time.Duration, bool) {
+	var sce *statusCodeError
+	if !errors.As(err, &sce) || sce.Header == nil {
+		return 0, false
+	}
+	ra := sce.Header.Get("Retry-After")
+	if len(ra) == 0 {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(ra); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// cloneRequest copies the request for another attempt, including a fresh body
+func cloneRequest(req *http.Request) *http.Request {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			r.Body = body
+		}
+	}
+	return r
+}
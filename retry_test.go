@@ -0,0 +1,166 @@
+package bridges
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHelper_HTTPCallWithOpts_Retry(t *testing.T) {
+	tests := []struct {
+		name       string
+		codes      []int
+		retryAfter string
+		policy     *RetryPolicy
+		attempts   int32
+		err        string
+	}{
+		{
+			"no policy",
+			[]int{http.StatusServiceUnavailable, http.StatusOK},
+			"",
+			nil,
+			1,
+			"Unexpected api status code: 503",
+		},
+		{
+			"retried until success",
+			[]int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			"",
+			&RetryPolicy{InitialBackoff: time.Millisecond},
+			3,
+			"",
+		},
+		{
+			"max attempts",
+			[]int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			"",
+			&RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			2,
+			"Unexpected api status code: 503",
+		},
+		{
+			"not retryable",
+			[]int{http.StatusNotFound, http.StatusOK},
+			"",
+			&RetryPolicy{InitialBackoff: time.Millisecond},
+			1,
+			"Unexpected api status code: 404",
+		},
+		{
+			"custom retryable codes",
+			[]int{http.StatusNotFound, http.StatusOK},
+			"",
+			&RetryPolicy{InitialBackoff: time.Millisecond, RetryableCodes: []int{http.StatusNotFound}},
+			2,
+			"",
+		},
+		{
+			"retry after",
+			[]int{http.StatusTooManyRequests, http.StatusOK},
+			"0",
+			&RetryPolicy{InitialBackoff: time.Hour},
+			2,
+			"",
+		},
+		{
+			"retry after exceeds max backoff",
+			[]int{http.StatusTooManyRequests, http.StatusOK},
+			"60",
+			&RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Second},
+			1,
+			"Unexpected api status code: 429",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := atomic.AddInt32(&attempts, 1) - 1
+				if len(test.retryAfter) > 0 {
+					w.Header().Set("Retry-After", test.retryAfter)
+				}
+				w.WriteHeader(test.codes[i])
+				fmt.Fprint(w, `{"price":1}`)
+			}))
+			defer upstream.Close()
+
+			r := make(map[string]interface{})
+			err := NewHelper(nil).HTTPCallWithOpts(http.MethodGet, upstream.URL, &r, CallOpts{Retry: test.policy})
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, float64(1), r["price"])
+			}
+			assert.Equal(t, test.attempts, atomic.LoadInt32(&attempts))
+		})
+	}
+}
+
+func TestHelper_HTTPCallRawWithOpts_RetryBody(t *testing.T) {
+	var attempts int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := make([]byte, 4)
+		n, _ := r.Body.Read(b)
+		assert.Equal(t, "body", string(b[:n]))
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	_, err := NewHelper(nil).HTTPCallRawWithOpts(http.MethodPost, upstream.URL, CallOpts{
+		Body:  "body",
+		Retry: &RetryPolicy{InitialBackoff: time.Millisecond},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	rp := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(t, 100*time.Millisecond, rp.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, rp.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, rp.backoff(3))
+	assert.Equal(t, time.Second, rp.backoff(5))
+
+	rp.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		b := rp.backoff(2)
+		assert.True(t, b >= 100*time.Millisecond && b <= 300*time.Millisecond)
+	}
+}
+
+func TestHelper_HTTPCallWithOpts_RetryCircuitOpen(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	s := NewServer()
+	h := s.newHelper(context.Background(), &HelloWorld{}, nil)
+	_, err := h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, CallOpts{
+		CircuitBreaker: &BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute},
+	})
+	assert.NotNil(t, err)
+
+	// An open breaker fails straight away, without waiting out any backoff
+	start := time.Now()
+	_, err = h.HTTPCallRawWithOpts(http.MethodGet, upstream.URL, CallOpts{
+		Retry:          &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second},
+		CircuitBreaker: &BreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute},
+	})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Less(t, int64(time.Since(start)), int64(100*time.Millisecond))
+}
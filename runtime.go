@@ -0,0 +1,105 @@
+package bridges
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/sirupsen/logrus"
+	"os"
+)
+
+// Supported runtimes of the server
+const (
+	RuntimeHTTP        = "http"
+	RuntimeLambda      = "lambda"
+	RuntimeGCP         = "gcp"
+	RuntimeCloudEvents = "cloudevents"
+)
+
+var runtimeNames = []string{RuntimeHTTP, RuntimeLambda, RuntimeGCP, RuntimeCloudEvents}
+
+// Runtime starts the server in the environment it's deployed to,
+// serving requests until the context is done
+type Runtime interface {
+	Start(ctx context.Context, s *Server) error
+}
+
+// NewRuntime returns the Runtime with the given name
+func NewRuntime(name string) (Runtime, error) {
+	switch name {
+	case RuntimeHTTP:
+		return HTTPRuntime{}, nil
+	case RuntimeLambda:
+		return LambdaRuntime{}, nil
+	case RuntimeGCP:
+		return GCPRuntime{}, nil
+	case RuntimeCloudEvents:
+		return CloudEventsRuntime{}, nil
+	}
+	return nil, fmt.Errorf("Unknown runtime %q", name)
+}
+
+// DetectRuntime returns the name of the runtime for the environment the
+// server is deployed to, being RuntimeLambda if the `LAMBDA` env is set,
+// RuntimeGCP if the `FUNCTION_TARGET` env set by GCP Functions is, and
+// otherwise RuntimeHTTP
+func DetectRuntime() string {
+	if len(os.Getenv("LAMBDA")) > 0 {
+		return RuntimeLambda
+	} else if len(os.Getenv("FUNCTION_TARGET")) > 0 {
+		return RuntimeGCP
+	}
+	return RuntimeHTTP
+}
+
+// HTTPRuntime serves the bridges on Addr with the inbuilt http server
+type HTTPRuntime struct{}
+
+// Start runs the server until the context is done
+func (HTTPRuntime) Start(ctx context.Context, s *Server) error {
+	logrus.WithField("addr", s.Addr).Info("Listening for requests")
+	return s.Run(ctx)
+}
+
+// LambdaRuntime serves the bridges as an AWS Lambda function,
+// handling events with LambdaEvent
+type LambdaRuntime struct{}
+
+// Start initialises the bridges and starts the Lambda handler,
+// which never returns
+func (LambdaRuntime) Start(ctx context.Context, s *Server) error {
+	if err := s.checkPaths(); err != nil {
+		return err
+	}
+	if err := s.initBridges(ctx); err != nil {
+		return err
+	}
+	lambda.Start(s.LambdaEvent)
+	return nil
+}
+
+// GCPRuntime serves the bridges as a GCP Function, listening on the `PORT`
+// env given by GCP. Requests are routed to the bridges by their path, and
+// CloudEvents such as those of Pub/Sub-triggered functions are handled by
+// CloudEventsHandler.
+type GCPRuntime struct{}
+
+// Start runs the server until the context is done
+func (GCPRuntime) Start(ctx context.Context, s *Server) error {
+	if port := os.Getenv("PORT"); len(port) > 0 {
+		s.Addr = ":" + port
+	}
+	logrus.WithField("addr", s.Addr).Info("Listening for requests")
+	return s.runHandler(ctx, s.eventHandler())
+}
+
+// CloudEventsRuntime serves the bridges on Addr, handling CloudEvents
+// delivered over http with CloudEventsHandler, such as by Knative Eventing
+// or Eventarc. Any other requests are routed to the bridges by their path.
+type CloudEventsRuntime struct{}
+
+// Start runs the server until the context is done
+func (CloudEventsRuntime) Start(ctx context.Context, s *Server) error {
+	logrus.WithField("addr", s.Addr).Info("Listening for CloudEvents")
+	return s.runHandler(ctx, s.eventHandler())
+}
@@ -0,0 +1,83 @@
+package bridges
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewRuntime(t *testing.T) {
+	for name, e := range map[string]Runtime{
+		RuntimeHTTP:        HTTPRuntime{},
+		RuntimeLambda:      LambdaRuntime{},
+		RuntimeGCP:         GCPRuntime{},
+		RuntimeCloudEvents: CloudEventsRuntime{},
+	} {
+		rt, err := NewRuntime(name)
+		assert.Nil(t, err)
+		assert.Equal(t, e, rt)
+	}
+	_, err := NewRuntime("azure")
+	assert.EqualError(t, err, `Unknown runtime "azure"`)
+}
+
+func TestDetectRuntime(t *testing.T) {
+	assert.Equal(t, RuntimeHTTP, DetectRuntime())
+
+	os.Setenv("FUNCTION_TARGET", "Handler")
+	defer os.Unsetenv("FUNCTION_TARGET")
+	assert.Equal(t, RuntimeGCP, DetectRuntime())
+
+	os.Setenv("LAMBDA", "1")
+	defer os.Unsetenv("LAMBDA")
+	assert.Equal(t, RuntimeLambda, DetectRuntime())
+}
+
+func TestGCPRuntime_Start(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	os.Setenv("PORT", strconv.Itoa(port))
+	defer os.Unsetenv("PORT")
+	s := NewServer(&LambdaPath{})
+	ctx, cancel := context.WithCancel(context.Background())
+	ec := make(chan error, 1)
+	go func() {
+		ec <- GCPRuntime{}.Start(ctx, s)
+	}()
+
+	url := "http://127.0.0.1:" + strconv.Itoa(port)
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = http.Get(url + HealthPath); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, url+"/path", bytes.NewReader([]byte(`{"id": "1234"}`)))
+	assert.Nil(t, err)
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", "1")
+	req.Header.Set("Ce-Source", "//chainlink/node")
+	req.Header.Set("Ce-Type", "com.chainlink.run")
+	resp, err = http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	assert.Nil(t, <-ec)
+	assert.Equal(t, ":"+strconv.Itoa(port), s.Addr)
+}
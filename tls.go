@@ -0,0 +1,133 @@
+package bridges
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Load returns the tls.Config serving the certificate, requiring and verifying
+// client certificates against the CA bundle if a ClientCAFile is given. The files
+// are reloaded when they change, so rotated certificates are served to new
+// connections without a restart.
+func (c *TLSConfig) Load() (*tls.Config, error) {
+	r := &tlsReloader{config: *c}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.configForClient,
+	}, nil
+}
+
+// tlsReloader holds the loaded certificate and client CAs, reloading
+// them once any of the files have been modified
+type tlsReloader struct {
+	config TLSConfig
+
+	mu  sync.RWMutex
+	tls *tls.Config
+	// modTimes are the modification times of each file when last loaded,
+	// and failed those of the last attempt that failed to load, so it's
+	// only retried and logged once the files change again
+	modTimes []time.Time
+	failed   []time.Time
+}
+
+func (r *tlsReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	if mts, err := r.fileModTimes(); err == nil && r.modified(mts) {
+		if err := r.reload(); err != nil {
+			logrus.Errorf("Failed to reload TLS certificates, serving the previous ones: %v", err)
+		} else {
+			logrus.Info("Reloaded TLS certificates")
+		}
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tls, nil
+}
+
+func (r *tlsReloader) modified(mts []time.Time) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !equalTimes(mts, r.modTimes) && !equalTimes(mts, r.failed)
+}
+
+// fileModTimes returns the modification time of each file, so any of them
+// changing is noticed even if it's replaced with an older file
+func (r *tlsReloader) fileModTimes() ([]time.Time, error) {
+	var mts []time.Time
+	for _, f := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if len(f) == 0 {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		mts = append(mts, fi.ModTime())
+	}
+	return mts, nil
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *tlsReloader) reload() error {
+	mts, err := r.fileModTimes()
+	if err != nil {
+		return err
+	}
+	tc, err := r.load()
+	if err != nil {
+		r.mu.Lock()
+		r.failed = mts
+		r.mu.Unlock()
+		return err
+	}
+
+	r.mu.Lock()
+	r.tls, r.modTimes, r.failed = tc, mts, nil
+	r.mu.Unlock()
+	return nil
+}
+
+// load loads the certificate and client CAs of the files
+func (r *tlsReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if len(r.config.ClientCAFile) > 0 {
+		pem, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in %s", r.config.ClientCAFile)
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
@@ -0,0 +1,250 @@
+package bridges
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+// newTestCert creates a certificate signed by the parent, or self-signed
+// as a CA if the parent is nil
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCert{cert: cert, key: key, pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+func (tc *testCert) write(t *testing.T, certFile, keyFile string) {
+	kb, err := x509.MarshalECPrivateKey(tc.key)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tc.cert.Raw}), 0600))
+	if len(keyFile) > 0 {
+		assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0600))
+	}
+}
+
+// serveTLS serves the server over TLS on a random port, returning its address
+func serveTLS(t *testing.T, s *Server) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	ec := make(chan error, 1)
+	go func() {
+		ec <- s.serve(ctx, ln, s.Mux())
+	}()
+	return ln.Addr().String(), func() {
+		cancel()
+		assert.Nil(t, <-ec)
+	}
+}
+
+func tlsGet(addr string, ca *testCert, client *testCert) (*http.Response, error) {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	tc := &tls.Config{RootCAs: pool}
+	if client != nil {
+		tc.Certificates = []tls.Certificate{client.pair}
+	}
+	c := &http.Client{Transport: &http.Transport{TLSClientConfig: tc, DisableKeepAlives: true}}
+	return c.Get("https://" + addr + "/health")
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridges")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", ca)
+	c := TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	server.write(t, c.CertFile, c.KeyFile)
+
+	s := NewServer(&Lifecycle{})
+	s.TLS = &c
+	addr, stop := serveTLS(t, s)
+	defer stop()
+
+	resp, err := tlsGet(addr, ca, nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://" + addr + "/health")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_TLS_ClientCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridges")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "server", ca)
+	c := TLSConfig{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	server.write(t, c.CertFile, c.KeyFile)
+	ca.write(t, c.ClientCAFile, "")
+
+	s := NewServer(&Lifecycle{})
+	s.TLS = &c
+	addr, stop := serveTLS(t, s)
+	defer stop()
+
+	resp, err := tlsGet(addr, ca, newTestCert(t, "node", ca))
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = tlsGet(addr, ca, nil)
+	assert.NotNil(t, err)
+
+	_, err = tlsGet(addr, ca, newTestCert(t, "node", newTestCert(t, "other", nil)))
+	assert.NotNil(t, err)
+}
+
+func TestServer_TLS_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridges")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil)
+	c := TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	newTestCert(t, "first", ca).write(t, c.CertFile, c.KeyFile)
+
+	s := NewServer(&Lifecycle{})
+	s.TLS = &c
+	addr, stop := serveTLS(t, s)
+	defer stop()
+
+	resp, err := tlsGet(addr, ca, nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "first", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	newTestCert(t, "second", ca).write(t, c.CertFile, c.KeyFile)
+	mt := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(c.KeyFile, mt, mt))
+
+	resp, err = tlsGet(addr, ca, nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// Keeps serving the previous certificate if the rotated one is invalid
+	assert.Nil(t, ioutil.WriteFile(c.CertFile, []byte("invalid"), 0600))
+	mt = mt.Add(time.Minute)
+	assert.Nil(t, os.Chtimes(c.CertFile, mt, mt))
+
+	resp, err = tlsGet(addr, ca, nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// Reloads files replaced with older ones, such as when copied preserving their times
+	newTestCert(t, "third", ca).write(t, c.CertFile, c.KeyFile)
+	mt = time.Now().Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(c.CertFile, mt, mt))
+	assert.Nil(t, os.Chtimes(c.KeyFile, mt, mt))
+
+	resp, err = tlsGet(addr, ca, nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "third", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func leafName(t *testing.T, tc *tls.Config) string {
+	cert, err := x509.ParseCertificate(tc.Certificates[0].Certificate[0])
+	assert.Nil(t, err)
+	return cert.Subject.CommonName
+}
+
+func TestTLSReloader_FailedOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bridges")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil)
+	c := TLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	newTestCert(t, "first", ca).write(t, c.CertFile, c.KeyFile)
+	r := &tlsReloader{config: c}
+	assert.Nil(t, r.reload())
+
+	hook := logtest.NewGlobal()
+	defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+
+	assert.Nil(t, ioutil.WriteFile(c.CertFile, []byte("invalid"), 0600))
+	mt := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(c.CertFile, mt, mt))
+
+	// The failed files are only tried once, until they change again
+	for i := 0; i < 3; i++ {
+		tc, err := r.configForClient(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "first", leafName(t, tc))
+	}
+	assert.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, logrus.ErrorLevel, hook.LastEntry().Level)
+
+	newTestCert(t, "second", ca).write(t, c.CertFile, c.KeyFile)
+	mt = mt.Add(time.Minute)
+	assert.Nil(t, os.Chtimes(c.CertFile, mt, mt))
+
+	tc, err := r.configForClient(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", leafName(t, tc))
+	assert.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+}
+
+func TestTLSConfig_Load_Invalid(t *testing.T) {
+	c := TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"}
+	_, err := c.Load()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,221 @@
+package bridges
+
+import (
+	"fmt"
+	"github.com/linkpoolio/bridges/abi"
+	"github.com/tidwall/gjson"
+	"math/big"
+	"strings"
+)
+
+// Common types of the Encode transformer
+const (
+	EncodeInt256  = "int256"
+	EncodeUint256 = "uint256"
+	EncodeBytes32 = "bytes32"
+	EncodeBool    = "bool"
+)
+
+// Transformer transforms the output of a bridge run before it's set as the
+// result data, such as picking a value out of an object or encoding it for
+// on-chain consumption
+type Transformer interface {
+	Transform(v *JSON) (*JSON, error)
+}
+
+// JSONParse is the Transformer that picks the value at the path of the output,
+// using the gjson path syntax, such as `RAW.ETH.USD.PRICE`
+type JSONParse struct {
+	Path string
+}
+
+// Transform returns the value at the path, erroring if it doesn't exist
+func (jp *JSONParse) Transform(v *JSON) (*JSON, error) {
+	r := v.Get(jp.Path)
+	if !r.Exists() {
+		return nil, fmt.Errorf("No value at path %q", jp.Path)
+	}
+	return &JSON{r}, nil
+}
+
+// Multiply is the Transformer that multiplies a numeric output by the decimal
+// number given in Times, such as `1e18`, without loss of precision. The product
+// is given as a string to keep its precision.
+type Multiply struct {
+	Times string
+}
+
+// Transform returns the product of the output and Times
+func (m *Multiply) Transform(v *JSON) (*JSON, error) {
+	t, ok := new(big.Rat).SetString(m.Times)
+	if !ok {
+		return nil, fmt.Errorf("Invalid multiplier: %s", m.Times)
+	}
+	n, ok := new(big.Rat).SetString(rawNumber(v.Result))
+	if !ok {
+		return nil, fmt.Errorf("Cannot multiply non-numeric value: %s", v.String())
+	}
+	return ParseInterface(decimalString(n.Mul(n, t)))
+}
+
+// Encode is the Transformer that ABI encodes the output as the Solidity type
+// signature, such as `uint256`, `bytes32` or `(uint256,string)` where the output
+// is an array of the tuple components. Integers truncate any decimals. The output
+// is replaced by an object with the hex encoding as `result` and the value that
+// was encoded as `value`.
+type Encode struct {
+	Type string
+}
+
+// Transform returns the ABI encoding of the output
+func (e *Encode) Transform(v *JSON) (*JSON, error) {
+	sig := e.Type
+	if strings.HasPrefix(sig, "eth") {
+		sig = sig[3:]
+	}
+	t, err := abi.ParseType(sig)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{abiValue(t, v.Result)}
+	if t.Kind == abi.TupleKind {
+		if !v.IsArray() {
+			return nil, fmt.Errorf("Cannot encode non-array value as %s", t)
+		}
+		values = values[0].([]interface{})
+	}
+
+	h, err := abi.EncodeToHex(t.String(), values...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInterface(map[string]interface{}{
+		"result": h,
+		"value":  rawValue(v),
+	})
+}
+
+// transform runs the output through the transformers of the bridge, followed
+// by those requested in the request data if enabled in the bridge options
+func (s *Server) transform(b Bridge, data *JSON, out *JSON) (*JSON, error) {
+	opts := b.Opts()
+	var err error
+	for _, t := range opts.Transformers {
+		if out, err = t.Transform(out); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.DataTransformers {
+		return out, nil
+	}
+
+	// Failures of the transformers requested by the node are due to its input
+	for _, dt := range dataTransformers(data) {
+		if out, err = dt.Transform(out); err != nil {
+			return nil, &ValidationError{Violations: []Violation{{
+				Field:   dt.key,
+				Rule:    "transform",
+				Message: err.Error(),
+			}}}
+		}
+	}
+	return out, nil
+}
+
+// dataTransformer is a transformer requested by a key in the request data
+type dataTransformer struct {
+	Transformer
+	key string
+}
+
+// dataTransformers returns the transformers requested by the reserved
+// `path`, `times` and `encode` keys in the request data
+func dataTransformers(data *JSON) []dataTransformer {
+	var ts []dataTransformer
+	if data == nil {
+		return ts
+	}
+	if p := data.Get("path"); p.IsArray() {
+		var keys []string
+		for _, k := range p.Array() {
+			keys = append(keys, k.String())
+		}
+		ts = append(ts, dataTransformer{&JSONParse{Path: strings.Join(keys, ".")}, "path"})
+	} else if p.Exists() {
+		ts = append(ts, dataTransformer{&JSONParse{Path: p.String()}, "path"})
+	}
+	if t := data.Get("times"); t.Exists() {
+		ts = append(ts, dataTransformer{&Multiply{Times: rawNumber(t)}, "times"})
+	}
+	if e := data.Get("encode"); e.Exists() {
+		ts = append(ts, dataTransformer{&Encode{Type: e.String()}, "encode"})
+	}
+	return ts
+}
+
+// decimalString returns the exact decimal representation of a product
+// of decimal numbers, which always terminates
+func decimalString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// the number of decimals is the highest power of 2 or 5 in the denominator
+	prec := 0
+	for _, f := range []int64{2, 5} {
+		n := 0
+		d, m := new(big.Int).Set(r.Denom()), new(big.Int)
+		for d.QuoRem(d, big.NewInt(f), m); m.Sign() == 0; d.QuoRem(d, big.NewInt(f), m) {
+			n++
+		}
+		if n > prec {
+			prec = n
+		}
+	}
+	return r.FloatString(prec)
+}
+
+// abiValue converts the JSON value to the Go value encoded as the type,
+// truncating the decimals of numbers encoded as integers
+func abiValue(t *abi.Type, v gjson.Result) interface{} {
+	switch t.Kind {
+	case abi.UintKind, abi.IntKind:
+		s := rawNumber(v)
+		if _, ok := parseBigInt(s); ok {
+			return s
+		}
+		if r, ok := new(big.Rat).SetString(s); ok {
+			return new(big.Int).Quo(r.Num(), r.Denom())
+		}
+		return s
+	case abi.BoolKind:
+		if v.Type == gjson.String && (v.Str == "true" || v.Str == "false") {
+			return v.Str == "true"
+		}
+		return v.Value()
+	case abi.SliceKind, abi.ArrayKind, abi.TupleKind:
+		if !v.IsArray() {
+			return v.Value()
+		}
+		vs := []interface{}{}
+		for i, e := range v.Array() {
+			if t.Kind != abi.TupleKind {
+				vs = append(vs, abiValue(t.Elem, e))
+			} else if i < len(t.Components) {
+				vs = append(vs, abiValue(t.Components[i], e))
+			} else {
+				vs = append(vs, e.Value())
+			}
+		}
+		return vs
+	}
+	return v.String()
+}
+
+// rawValue returns the value as given, keeping the raw text of numbers
+func rawValue(v *JSON) interface{} {
+	if v.Type == gjson.Number {
+		return v.Raw
+	}
+	return v.Value()
+}
@@ -0,0 +1,99 @@
+package bridges
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestTransformers(t *testing.T) {
+	in, err := Parse([]byte(`{"RAW":{"ETH":{"USD":{"PRICE":123.456789}}}}`))
+	assert.Nil(t, err)
+
+	v, err := (&JSONParse{Path: "RAW.ETH.USD.PRICE"}).Transform(in)
+	assert.Nil(t, err)
+	assert.Equal(t, "123.456789", v.Raw)
+	_, err = (&JSONParse{Path: "RAW.BTC"}).Transform(in)
+	assert.EqualError(t, err, `No value at path "RAW.BTC"`)
+
+	v, err = (&Multiply{Times: "1e18"}).Transform(v)
+	assert.Nil(t, err)
+	assert.Equal(t, "123456789000000000000", v.String())
+	v, err = (&Multiply{Times: "0.5"}).Transform(&JSON{in.Get("RAW.ETH.USD.PRICE")})
+	assert.Nil(t, err)
+	assert.Equal(t, "61.7283945", v.String())
+	_, err = (&Multiply{Times: "10"}).Transform(&JSON{in.Get("RAW")})
+	assert.NotNil(t, err)
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		typ    string
+		in     string
+		result string
+		err    string
+	}{
+		{EncodeUint256, `"123456789000000000000"`, "0x000000000000000000000000000000000000000000000006b14e9f7e4f5a5000", ""},
+		{EncodeUint256, `1.9`, "0x0000000000000000000000000000000000000000000000000000000000000001", ""},
+		{EncodeUint256, `-1`, "", "Value out of range of uint256: -1"},
+		{"ethint256", `-1`, "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", ""},
+		{EncodeInt256, `"0x8000000000000000000000000000000000000000000000000000000000000000"`, "",
+			"Value out of range of int256: 57896044618658097711785492504343953926634992332820282019728792003956564819968"},
+		{EncodeBytes32, `"ETH"`, "0x4554480000000000000000000000000000000000000000000000000000000000", ""},
+		{EncodeBool, `true`, "0x0000000000000000000000000000000000000000000000000000000000000001", ""},
+		{EncodeBool, `"yes"`, "", "Invalid bool value: yes"},
+		{"(uint256,string)", `["1.5","ETH"]`, "0x0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000040" +
+			"0000000000000000000000000000000000000000000000000000000000000003" +
+			"4554480000000000000000000000000000000000000000000000000000000000", ""},
+		{"(uint256,string)", `1`, "", "Cannot encode non-array value as (uint256,string)"},
+		{"fixed", `1`, "", "Unsupported type: fixed"},
+	}
+	for _, test := range tests {
+		t.Run(test.typ, func(t *testing.T) {
+			in, err := Parse([]byte(test.in))
+			assert.Nil(t, err)
+			v, err := (&Encode{Type: test.typ}).Transform(in)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.result, v.Get("result").String())
+		})
+	}
+}
+
+type Transform struct{}
+
+func (tr *Transform) Run(h *Helper) (interface{}, error) {
+	return map[string]interface{}{"data": map[string]interface{}{"price": 1.5}}, nil
+}
+
+func (tr *Transform) Opts() *Opts {
+	return &Opts{
+		Name:             "Transform",
+		Transformers:     []Transformer{&JSONParse{Path: "data"}},
+		DataTransformers: true,
+	}
+}
+
+func TestServer_Mux_Transformers(t *testing.T) {
+	mux := NewServer(&Transform{}).Mux()
+
+	code, json := postRun(t, mux, map[string]interface{}{
+		"id":   "1234",
+		"data": map[string]interface{}{"path": []string{"price"}, "times": 100, "encode": "ethuint256"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000096", json.Get("data.result").String())
+	assert.Equal(t, "150", json.Get("data.value").String())
+
+	code, json = postRun(t, mux, map[string]interface{}{"id": "1234", "data": map[string]interface{}{"path": "volume"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `Invalid request data: No value at path "volume"`, json.Get("error").String())
+
+	code, json = postRun(t, mux, map[string]interface{}{"id": "1234", "data": map[string]interface{}{"path": "price", "encode": "ethint7"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "errored", json.Get("status").String())
+}